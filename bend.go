@@ -0,0 +1,98 @@
+package midi
+
+import (
+	"fmt"
+	"math"
+)
+
+// Registered parameter number controllers
+const (
+	controllerDataEntryMSB = 6
+	controllerDataEntryLSB = 38
+	controllerRPNLSB       = 100
+	controllerRPNMSB       = 101
+)
+
+// bendSpan is a pitch bend that is in use on a channel, from the start tick to the end tick
+type bendSpan struct {
+	start, end uint32
+	value      uint16
+}
+
+// pitchBendValue converts an offset in semitones to a 14-bit pitch bend value,
+// where 8192 is the center, for the given pitch bend range in semitones
+func pitchBendValue(semitones, bendRange float64) uint16 {
+	value := math.Round(8192 + semitones/bendRange*8192)
+	if value < 0 {
+		return 0
+	} else if value > 16383 {
+		return 16383
+	}
+	return uint16(value)
+}
+
+// allocateBendChannel finds a channel for a note with the given pitch bend.
+// The preferred channel is used if no other note is playing on it with a different
+// pitch bend, otherwise the first free channel in MPEChannels is used. An error is
+// returned if there is no free channel.
+func (m *MIDI) allocateBendChannel(preferred uint8, start, end uint32, value uint16) (uint8, error) {
+	if m.bendSpans == nil {
+		m.bendSpans = make(map[uint8][]bendSpan)
+	}
+	for _, channel := range append([]uint8{preferred}, m.MPEChannels...) {
+		if m.bendIsFree(channel, start, end, value) {
+			m.bendSpans[channel] = append(m.bendSpans[channel], bendSpan{start: start, end: end, value: value})
+			return channel, nil
+		}
+	}
+	return 0, fmt.Errorf("no free channel for a pitch bend from tick %d to %d, all the channels play notes with other pitch bends", start, end)
+}
+
+// bendIsFree checks if a pitch bend can be used on a channel without disturbing other notes
+func (m *MIDI) bendIsFree(channel uint8, start, end uint32, value uint16) bool {
+	for _, span := range m.bendSpans[channel] {
+		if span.start < end && start < span.end && span.value != value {
+			return false
+		}
+	}
+	return true
+}
+
+// addBendRange adds events that set the pitch bend range of a channel with
+// RPN 0, the first time a pitch bend is used on that channel
+func (m *MIDI) addBendRange(t *Track, channel uint8) {
+	if m.bendRangeSent == nil {
+		m.bendRangeSent = make(map[uint8]bool)
+	}
+	if m.bendRangeSent[channel] {
+		return
+	}
+	m.bendRangeSent[channel] = true
+
+	semitones := math.Floor(m.PitchBendRange)
+	cents := math.Round((m.PitchBendRange - semitones) * 100)
+	addRPN(t, channel, 0, 0, uint8(math.Min(semitones, 127)), uint8(math.Min(cents, 99)))
+}
+
+// addRPN adds events at the start of a track that set a registered parameter number
+// on a channel, with up to two data entry values, the MSB and the LSB
+func addRPN(t *Track, channel, msb, lsb uint8, data ...uint8) {
+	controllers := [][2]uint8{
+		{controllerRPNMSB, msb},
+		{controllerRPNLSB, lsb},
+	}
+	dataEntry := []uint8{controllerDataEntryMSB, controllerDataEntryLSB}
+	for i := 0; i < len(data) && i < len(dataEntry); i++ {
+		controllers = append(controllers, [2]uint8{dataEntry[i], data[i]})
+	}
+	// Deselect the RPN, so that later data entry messages do not change it
+	controllers = append(controllers, [2]uint8{controllerRPNMSB, 127}, [2]uint8{controllerRPNLSB, 127})
+	for _, controller := range controllers {
+		t.AddEvent(&Event{
+			Tick:    0,
+			Type:    ControlChange,
+			Channel: channel,
+			Data:    []byte{controller[0], controller[1]},
+		})
+	}
+}
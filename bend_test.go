@@ -0,0 +1,120 @@
+package midi
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAddNotePitchBend(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	m.PitchBendRange = 2
+	track := NewTrack()
+	m.AddTrack(track)
+
+	// 25 cents above A4
+	m.AddNote(track, &Note{
+		Frequency: 440 * math.Pow(2, 0.25/12),
+		Duration:  time.Second,
+		Velocity:  DefaultNoteVelocity,
+		Channel:   DefaultNoteChannel,
+	})
+
+	var rpn, bends []*Event
+	for _, e := range track.Events {
+		switch e.Type {
+		case ControlChange:
+			rpn = append(rpn, e)
+		case PitchBend:
+			bends = append(bends, e)
+		}
+	}
+	if len(rpn) != 6 || rpn[0].Data[0] != 101 || rpn[2].Data[0] != 6 || rpn[2].Data[1] != 2 {
+		t.Errorf("Expected RPN 0 events setting the pitch bend range to 2 semitones, got %d control changes", len(rpn))
+	}
+	if len(bends) != 1 {
+		t.Fatalf("Expected 1 pitch bend event, got %d", len(bends))
+	}
+	// 25 cents is an eighth of the 2 semitone range above the center
+	value := uint16(bends[0].Data[0]) | uint16(bends[0].Data[1])<<7
+	if value != 8192+1024 {
+		t.Errorf("Pitch bend value = %d, want %d", value, 8192+1024)
+	}
+}
+
+func TestAddNoteMPE(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	m.PitchBendRange = 2
+	m.MPEChannels = []uint8{2, 3, 4}
+	track := NewTrack()
+	m.AddTrack(track)
+
+	frequencies := []float64{
+		440,                          // A4
+		440 * math.Pow(2, 4/12.0),    // C#5
+		440 * math.Pow(2, 7.3/12.0),  // E5, slightly sharp
+		440 * math.Pow(2, 12.3/12.0), // A5, slightly sharp, same bend as E5
+	}
+	for _, frequency := range frequencies {
+		m.AddNote(track, &Note{
+			Frequency: frequency,
+			Duration:  time.Second,
+			Velocity:  DefaultNoteVelocity,
+			Channel:   DefaultNoteChannel,
+		})
+	}
+
+	channels := make(map[uint8]uint8)
+	for _, e := range track.Events {
+		if e.Type == EventNoteOn {
+			channels[e.Data[0]] = e.Channel
+		}
+	}
+	if channels[69] != 1 || channels[73] != 1 {
+		t.Errorf("Notes without pitch bend should stay on channel 1, got %d and %d", channels[69], channels[73])
+	}
+	if channels[76] != 2 || channels[81] != 2 {
+		t.Errorf("Notes with the same pitch bend should share channel 2, got %d and %d", channels[76], channels[81])
+	}
+}
+
+func TestAddNoteNoPitchBend(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	track := NewTrack()
+	m.AddTrack(track)
+	m.AddNote(track, &Note{Frequency: 445, Duration: time.Second, Channel: DefaultNoteChannel})
+	for _, e := range track.Events {
+		if e.Type == PitchBend || e.Type == ControlChange {
+			t.Errorf("Unexpected event %X when PitchBendRange is 0", e.Type)
+		}
+	}
+}
+
+func TestAddNoteNoFreeChannel(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	m.PitchBendRange = 2
+	m.MPEChannels = []uint8{2}
+	track := NewTrack()
+	m.AddTrack(track)
+
+	// Three notes at the same time, with three different pitch bends
+	for _, cents := range []float64{0, 30, -30} {
+		err := m.AddNote(track, &Note{
+			Frequency: 440 * math.Pow(2, cents/1200),
+			Duration:  time.Second,
+			Velocity:  DefaultNoteVelocity,
+			Channel:   DefaultNoteChannel,
+		})
+		if cents != -30 && err != nil {
+			t.Fatalf("AddNote failed: %v", err)
+		}
+		if cents == -30 && err == nil {
+			t.Errorf("Expected an error when no channel is free for the pitch bend")
+		}
+	}
+	for _, e := range track.Events {
+		if e.Type == PitchBend && e.Data[1] < 64 {
+			t.Errorf("The note without a free channel should not add a pitch bend on channel %d", e.Channel)
+		}
+	}
+}
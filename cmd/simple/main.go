@@ -38,7 +38,9 @@ func main() {
 		for _, note := range chord {
 			t := midi.NewTrack()
 			m.AddTrack(t)
-			m.AddNote(t, &note)
+			if err := m.AddNote(t, &note); err != nil {
+				log.Fatal(err)
+			}
 		}
 	}
 
@@ -0,0 +1,164 @@
+package midi
+
+import (
+	"bufio"
+	"io"
+)
+
+// DefaultMaxSysEx is the default maximum length of the data of a system exclusive
+// message for a Decoder
+const DefaultMaxSysEx = 64 * 1024
+
+// Decoder reads MIDI messages from a stream of raw MIDI bytes, like a serial port,
+// a pipe or a socket. There are no delta times in the stream, so the Tick and
+// DeltaTime of the decoded events are 0.
+type Decoder struct {
+	r io.ByteReader
+
+	status  uint8  // the running status, 0 if there is none
+	message uint8  // the status of the message that is being read, 0 if there is none
+	needed  int    // the number of data bytes in the message that is being read
+	data    []byte // the data bytes that have been read for the message
+
+	inSysEx   bool
+	sysex     []byte
+	sysexSkip bool // if the system exclusive message is too long, and is being skipped
+
+	pending    uint8 // a status byte that ended a system exclusive message
+	hasPending bool
+
+	// MaxSysEx is the maximum length of the data of a system exclusive message.
+	// Longer messages are skipped. DefaultMaxSysEx is used if it is 0.
+	MaxSysEx int
+
+	// Skipped is the number of bytes that have been skipped, because they were malformed
+	Skipped int
+}
+
+// NewDecoder creates a new Decoder that reads from an io.Reader
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{r: br}
+}
+
+// Decode reads the next MIDI message. Channel messages have the type in the high
+// nibble and the channel from 1 to 16, like in a Track. System messages have the
+// full status byte as the type, except System Reset, which is returned as
+// NewSystemReset, since its status byte is the same as for meta events. Real-time
+// messages are returned as soon as they are read, even in the middle of other
+// messages. System exclusive messages have the data after 0xF0, and end with 0xF7.
+func (d *Decoder) Decode() (*Event, error) {
+	for {
+		b, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case b >= TimingClock:
+			// Real-time messages do not affect the running status or the message that is being read
+			switch b {
+			case 0xF9, 0xFD:
+				d.Skipped++ // undefined
+				continue
+			case SystemReset:
+				return NewSystemReset(), nil
+			}
+			return &Event{Type: b}, nil
+		case d.inSysEx && b < 0x80:
+			if d.sysexSkip {
+				d.Skipped++
+				continue
+			}
+			if len(d.sysex) >= d.maxSysEx() {
+				// The message is too long, so it is skipped until it ends
+				d.Skipped += 1 + len(d.sysex) + 1
+				d.sysex, d.sysexSkip = nil, true
+				continue
+			}
+			d.sysex = append(d.sysex, b)
+			continue
+		case d.inSysEx:
+			// Any other status byte ends a system exclusive message
+			d.inSysEx = false
+			data := append(d.sysex, EndOfExclusive)
+			skipped := d.sysexSkip
+			d.sysex, d.sysexSkip = nil, false
+			if b != EndOfExclusive {
+				d.pending, d.hasPending = b, true
+			} else if skipped {
+				d.Skipped++
+			}
+			if skipped {
+				continue
+			}
+			return &Event{Type: EventSysEx, Data: data}, nil
+		case b == SystemExclusive:
+			d.status, d.message, d.data = 0, 0, nil
+			d.inSysEx, d.sysex, d.sysexSkip = true, []byte{}, false
+			continue
+		case b >= 0xF0:
+			// System common messages cancel the running status
+			d.status, d.message, d.data = 0, 0, nil
+			switch b {
+			case MIDITimeCode, SongSelect:
+				d.message, d.needed = b, 1
+			case SongPosition:
+				d.message, d.needed = b, 2
+			case TuneRequest:
+				return &Event{Type: b}, nil
+			default:
+				d.Skipped++ // undefined, or 0xF7 outside of a system exclusive message
+			}
+			continue
+		case b >= 0x80:
+			if d.message != 0 {
+				d.Skipped += 1 + len(d.data) // an incomplete message
+			}
+			d.status, d.message, d.data = b, b, nil
+			d.needed = channelMessageLength(b & 0xF0)
+			continue
+		}
+
+		// This is a data byte
+		if d.message == 0 {
+			if d.status == 0 {
+				d.Skipped++
+				continue
+			}
+			d.message = d.status
+			d.needed = channelMessageLength(d.status & 0xF0)
+		}
+		d.data = append(d.data, b)
+		if len(d.data) < d.needed {
+			continue
+		}
+		e := &Event{Type: d.message, Data: d.data}
+		if isChannelMessage(d.message) {
+			e.Type = d.message & 0xF0
+			e.Channel = d.message&0x0F + 1
+		}
+		d.message, d.data = 0, nil
+		return e, nil
+	}
+}
+
+// maxSysEx returns the maximum length of the data of a system exclusive message
+func (d *Decoder) maxSysEx() int {
+	if d.MaxSysEx > 0 {
+		return d.MaxSysEx
+	}
+	return DefaultMaxSysEx
+}
+
+// readByte returns the next byte, or the byte that ended the last system exclusive message
+func (d *Decoder) readByte() (uint8, error) {
+	if d.hasPending {
+		d.hasPending = false
+		return d.pending, nil
+	}
+	return d.r.ReadByte()
+}
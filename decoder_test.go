@@ -0,0 +1,126 @@
+package midi
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// decodeAll decodes all the messages in a byte slice
+func decodeAll(t *testing.T, data []byte) ([]*Event, *Decoder) {
+	d := NewDecoder(bytes.NewReader(data))
+	var events []*Event
+	for {
+		e, err := d.Decode()
+		if err == io.EOF {
+			return events, d
+		}
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		events = append(events, e)
+	}
+}
+
+func TestDecodeRunningStatus(t *testing.T) {
+	events, _ := decodeAll(t, []byte{0x91, 60, 100, 64, 100, 60, 0, 0xC1, 5, 6})
+	if len(events) != 5 {
+		t.Fatalf("Decoded %d events, want 5", len(events))
+	}
+	for _, e := range events[:3] {
+		if e.Type != NoteOn || e.Channel != 2 {
+			t.Errorf("Event = %+v, want a note on for channel 2", e)
+		}
+	}
+	if !bytes.Equal(events[2].Data, []byte{60, 0}) {
+		t.Errorf("Third event data = %X, want 3C 00", events[2].Data)
+	}
+	if events[4].Type != ProgramChange || events[4].Data[0] != 6 {
+		t.Errorf("Running status program change = %+v", events[4])
+	}
+}
+
+func TestDecodeRealTime(t *testing.T) {
+	// Timing clocks in the middle of a note on, and in a system exclusive message
+	events, d := decodeAll(t, []byte{0x90, 0xF8, 60, 0xFE, 100, 0xF0, 0x7E, 0xF8, 0x01, 0xF7, 62, 80})
+	types := []uint8{TimingClock, ActiveSensing, NoteOn, TimingClock, EventSysEx}
+	if len(events) != len(types) {
+		t.Fatalf("Decoded %d events, want %d", len(events), len(types))
+	}
+	for i, e := range events {
+		if e.Type != types[i] {
+			t.Errorf("Event %d has type %X, want %X", i, e.Type, types[i])
+		}
+	}
+	if !bytes.Equal(events[4].Data, []byte{0x7E, 0x01, 0xF7}) {
+		t.Errorf("System exclusive data = %X, want 7E 01 F7", events[4].Data)
+	}
+	// The system exclusive message cancels the running status, so the last two bytes are skipped
+	if d.Skipped != 2 {
+		t.Errorf("Skipped = %d, want 2", d.Skipped)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	data := []byte{
+		60, 100, // data bytes without a status
+		0x90, 60, // incomplete note on
+		0xB0, 7, 100, // control change
+		0xF0, 0x41, 0x10, // system exclusive, ended by a status byte
+		0x80, 60, 0,
+		0xF4,             // undefined
+		0xF2, 0x10, 0x20, // song position
+		0xF6, // tune request
+	}
+	events, d := decodeAll(t, data)
+	types := []uint8{ControlChange, EventSysEx, NoteOff, SongPosition, TuneRequest}
+	if len(events) != len(types) {
+		t.Fatalf("Decoded %d events, want %d", len(events), len(types))
+	}
+	for i, e := range events {
+		if e.Type != types[i] {
+			t.Errorf("Event %d has type %X, want %X", i, e.Type, types[i])
+		}
+	}
+	if !bytes.Equal(events[1].Data, []byte{0x41, 0x10, 0xF7}) {
+		t.Errorf("Interrupted system exclusive data = %X, want 41 10 F7", events[1].Data)
+	}
+	if d.Skipped != 5 {
+		t.Errorf("Skipped = %d, want 5", d.Skipped)
+	}
+}
+
+func TestDecodeSystemReset(t *testing.T) {
+	events, _ := decodeAll(t, []byte{0xFF})
+	if len(events) != 1 || !events[0].IsSystemReset() {
+		t.Fatalf("Decoded %+v, want a System Reset", events)
+	}
+	if events[0].IsMeta(MetaSequenceNumber) {
+		t.Error("A System Reset should not be a meta event")
+	}
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(events[0]); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), []byte{0xFF}) {
+		t.Errorf("Encoded System Reset = %X, want FF", buf.Bytes())
+	}
+}
+
+func TestDecodeLongSysEx(t *testing.T) {
+	data := []byte{0xF0}
+	data = append(data, bytes.Repeat([]byte{0x01}, 10)...)
+	data = append(data, 0xF7, 0xF0, 0x02, 0xF7)
+	d := NewDecoder(bytes.NewReader(data))
+	d.MaxSysEx = 8
+	e, err := d.Decode()
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if e.Type != EventSysEx || !bytes.Equal(e.Data, []byte{0x02, 0xF7}) {
+		t.Errorf("Decoded %+v, want the short system exclusive message", e)
+	}
+	if d.Skipped != 12 {
+		t.Errorf("Skipped = %d, want 12", d.Skipped)
+	}
+}
@@ -0,0 +1,138 @@
+package midi
+
+import (
+	"fmt"
+	"io"
+)
+
+// Encoder writes MIDI events to an io.Writer, either as raw MIDI bytes for live
+// devices, or with delta times like in a track of a Standard MIDI File
+type Encoder struct {
+	w io.Writer
+
+	// SMF makes the encoder write the delta time before each event, and write meta
+	// events and the length of system exclusive events, like in a Standard MIDI File.
+	// Otherwise meta events are skipped, since they can not be sent to devices,
+	// while System Reset events from NewSystemReset are sent.
+	SMF bool
+
+	// RunningStatus leaves out the status byte of channel messages when it is the
+	// same as for the previous channel message
+	RunningStatus bool
+
+	// NoteOffAsNoteOn writes "note off" events as "note on" events with velocity 0,
+	// which lets more events share the running status
+	NoteOffAsNoteOn bool
+
+	status uint8 // the running status, 0 if there is none
+}
+
+// NewEncoder creates a new Encoder that writes raw MIDI bytes to an io.Writer
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Reset clears the running status, so that the next event is written with a status byte
+func (enc *Encoder) Reset() {
+	enc.status = 0
+}
+
+// Encode writes an event
+func (enc *Encoder) Encode(e *Event) error {
+	if !enc.SMF && e.Type == EventMeta && !e.IsSystemReset() {
+		return nil
+	}
+	if enc.SMF && (e.IsSystemReset() || e.Type > EventSysEx && e.Type != EventSysExEscape && e.Type != EventMeta) {
+		return fmt.Errorf("system message %X can not be stored in a MIDI file", e.Type)
+	}
+
+	// Write delta time
+	if enc.SMF {
+		if err := writeVariableLengthQuantity(enc.w, e.DeltaTime); err != nil {
+			return err
+		}
+	}
+
+	// Write event type, combined with the channel for channel messages
+	status, err := statusByte(e)
+	if err != nil {
+		return err
+	}
+	data := e.Data
+	if enc.NoteOffAsNoteOn && e.Type == NoteOff && len(data) == 2 {
+		status = NoteOn | status&0x0F
+		data = []byte{data[0], 0}
+	}
+
+	switch {
+	case isChannelMessage(e.Type):
+		if !enc.RunningStatus || status != enc.status {
+			if err := writeMIDIUint8(enc.w, status); err != nil {
+				return err
+			}
+		}
+		enc.status = status
+	case e.Type >= TimingClock && !enc.SMF:
+		// Real-time messages do not affect the running status
+		if err := writeMIDIUint8(enc.w, status); err != nil {
+			return err
+		}
+	case e.Type == EventSysExEscape && !enc.SMF:
+		// Escaped data is sent as it is
+		enc.status = 0
+	default:
+		// Meta, system exclusive and system common messages cancel the running status
+		enc.status = 0
+		if err := writeMIDIUint8(enc.w, status); err != nil {
+			return err
+		}
+	}
+
+	if enc.SMF {
+		switch e.Type {
+		case EventMeta:
+			// Meta events are followed by the meta type and the data length
+			if err := writeMIDIUint8(enc.w, e.Meta); err != nil {
+				return err
+			}
+			if err := writeVariableLengthQuantity(enc.w, uint32(len(data))); err != nil {
+				return err
+			}
+		case EventSysEx, EventSysExEscape:
+			// System exclusive events are followed by the data length
+			if err := writeVariableLengthQuantity(enc.w, uint32(len(data))); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Write event data
+	return writeBytes(enc.w, data)
+}
+
+// EncodeMIDI writes a Standard MIDI File, with the RunningStatus and
+// NoteOffAsNoteOn settings of the encoder. An End of Track event is added to
+// each track, and a Set Tempo event is added from the BPM if there is none.
+// Format 0 must have exactly one track.
+func (enc *Encoder) EncodeMIDI(m *MIDI) error {
+	if err := m.validateFormat(); err != nil {
+		return err
+	}
+
+	// A tempo event is added if there is none
+	tracks := m.outputTracks()
+
+	// Write MIDI header
+	if err := writeMIDIHeader(enc.w, m, uint16(len(tracks))); err != nil {
+		return err
+	}
+
+	// Write each track
+	for _, track := range tracks {
+		if err := writeTrack(enc.w, track, enc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
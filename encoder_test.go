@@ -0,0 +1,115 @@
+package midi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncoderLive(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf)
+	enc.RunningStatus = true
+	enc.NoteOffAsNoteOn = true
+
+	noteOn, _ := NewNoteOn(1, 60, 100)
+	noteOff, _ := NewNoteOff(1, 60, 64)
+	sysex, _ := NewSysEx([]byte{0x7E, 0x7F, 0x09, 0x01})
+	events := []*Event{
+		noteOn,
+		{Type: TimingClock},
+		noteOff,
+		NewTrackName("skipped"),
+		sysex,
+		noteOn,
+	}
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+
+	want := []byte{
+		0x90, 60, 100,
+		0xF8,  // real-time messages do not cancel the running status
+		60, 0, // note off as note on with velocity 0, with running status
+		0xF0, 0x7E, 0x7F, 0x09, 0x01, 0xF7,
+		0x90, 60, 100, // system exclusive messages cancel the running status
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Encoded % X, want % X", buf.Bytes(), want)
+	}
+
+	// The decoder should read the same events back, without the meta event
+	d := NewDecoder(buf)
+	for i, wantType := range []uint8{NoteOn, TimingClock, NoteOn, EventSysEx, NoteOn} {
+		e, err := d.Decode()
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if e.Type != wantType {
+			t.Errorf("Decoded event %d has type %X, want %X", i, e.Type, wantType)
+		}
+	}
+}
+
+func TestEncodeMIDIRunningStatus(t *testing.T) {
+	m := NewMIDI(0, 480, 120)
+	track := NewTrack()
+	m.AddTrack(track)
+	for i := uint32(0); i < 8; i++ {
+		noteOn, _ := NewNoteOn(1, uint8(60+i), 100)
+		noteOff, _ := NewNoteOff(1, uint8(60+i), 0)
+		track.AddEventAt(i*240, noteOn)
+		track.AddEventAt(i*240+120, noteOff)
+	}
+
+	plain := new(bytes.Buffer)
+	if err := m.Write(plain); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	compressed := new(bytes.Buffer)
+	enc := NewEncoder(compressed)
+	enc.RunningStatus = true
+	enc.NoteOffAsNoteOn = true
+	if err := enc.EncodeMIDI(m); err != nil {
+		t.Fatalf("EncodeMIDI failed: %v", err)
+	}
+
+	// All but the first note event can leave out the status byte
+	if saved := plain.Len() - compressed.Len(); saved != 15 {
+		t.Errorf("Running status saved %d bytes, want 15", saved)
+	}
+
+	m2, err := Read(compressed)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	notes := 0
+	for _, e := range m2.Tracks[0].Events {
+		if e.Type == EventNoteOn {
+			notes++
+			if e.Channel != 1 {
+				t.Errorf("Note is on channel %d, want 1", e.Channel)
+			}
+		}
+	}
+	if notes != 16 {
+		t.Errorf("Read %d note on events, want 16", notes)
+	}
+}
+
+func TestEncoderSMFSystemMessage(t *testing.T) {
+	enc := NewEncoder(new(bytes.Buffer))
+	enc.SMF = true
+	if err := enc.Encode(&Event{Type: TimingClock}); err == nil {
+		t.Error("Encode should fail for a real-time message in SMF mode")
+	}
+}
+
+func TestEncoderSMFSystemReset(t *testing.T) {
+	enc := NewEncoder(new(bytes.Buffer))
+	enc.SMF = true
+	if err := enc.Encode(NewSystemReset()); err == nil {
+		t.Error("Encode should fail for a System Reset in SMF mode")
+	}
+}
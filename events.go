@@ -0,0 +1,118 @@
+package midi
+
+import (
+	"fmt"
+	"time"
+)
+
+// NewNoteOn creates a new "note on" event
+func NewNoteOn(channel, note, velocity uint8) (*Event, error) {
+	return newChannelEvent(NoteOn, channel, note, velocity)
+}
+
+// NewNoteOff creates a new "note off" event
+func NewNoteOff(channel, note, velocity uint8) (*Event, error) {
+	return newChannelEvent(NoteOff, channel, note, velocity)
+}
+
+// NewAftertouch creates a new polyphonic key pressure event, for a single key
+func NewAftertouch(channel, note, pressure uint8) (*Event, error) {
+	return newChannelEvent(PolyphonicKeyPressure, channel, note, pressure)
+}
+
+// NewControlChange creates a new control change event
+func NewControlChange(channel, controller, value uint8) (*Event, error) {
+	return newChannelEvent(ControlChange, channel, controller, value)
+}
+
+// NewProgramChange creates a new program change event
+func NewProgramChange(channel, program uint8) (*Event, error) {
+	e, err := newChannelEvent(ProgramChange, channel, program)
+	if err != nil {
+		return nil, err
+	}
+	e.Program = program
+	return e, nil
+}
+
+// NewChannelPressure creates a new channel pressure event, for all the keys on a channel
+func NewChannelPressure(channel, pressure uint8) (*Event, error) {
+	return newChannelEvent(ChannelPressure, channel, pressure)
+}
+
+// NewPitchBend creates a new pitch bend event. The value is from -8192 to 8191, where 0 is no bend.
+func NewPitchBend(channel uint8, value int) (*Event, error) {
+	if value < -8192 || value > 8191 {
+		return nil, fmt.Errorf("invalid pitch bend value %d, must be from -8192 to 8191", value)
+	}
+	bend := uint16(value + 8192)
+	return newChannelEvent(PitchBend, channel, byte(bend&0x7F), byte(bend>>7))
+}
+
+// NewSysEx creates a new system exclusive event. The data may start with 0xF0,
+// and a terminating 0xF7 is added if it is missing.
+func NewSysEx(data []byte) (*Event, error) {
+	if len(data) > 0 && data[0] == SystemExclusive {
+		data = data[1:]
+	}
+	if len(data) == 0 || data[len(data)-1] != EventSysExEscape {
+		data = append(append([]byte{}, data...), EventSysExEscape)
+	}
+	for _, b := range data[:len(data)-1] {
+		if b&0x80 != 0 {
+			return nil, fmt.Errorf("invalid system exclusive data byte %X", b)
+		}
+	}
+	return &Event{
+		Type: EventSysEx,
+		Data: data,
+	}, nil
+}
+
+// NewSystemReset creates a new System Reset event, for sending to devices. Its
+// status byte is the same as for meta events in MIDI files, so it is marked as a
+// System Reset in a way that a meta event read from a file can not be.
+func NewSystemReset() *Event {
+	return &Event{Type: SystemReset, systemReset: true}
+}
+
+// IsSystemReset checks if an event is a System Reset event, as created by NewSystemReset
+func (e *Event) IsSystemReset() bool {
+	return e.Type == SystemReset && e.systemReset
+}
+
+// newChannelEvent creates a new channel message, and checks the channel and the data bytes
+func newChannelEvent(eventType, channel uint8, data ...uint8) (*Event, error) {
+	if channel < 1 || channel > 16 {
+		return nil, fmt.Errorf("invalid channel %d, must be from 1 to 16", channel)
+	}
+	for _, b := range data {
+		if b > 127 {
+			return nil, fmt.Errorf("invalid data value %d, must be from 0 to 127", b)
+		}
+	}
+	return &Event{
+		Type:    eventType,
+		Channel: channel,
+		Data:    data,
+	}, nil
+}
+
+// PitchBendValue returns the value of a pitch bend event, from -8192 to 8191
+func (e *Event) PitchBendValue() (int, bool) {
+	if e.Type != PitchBend || len(e.Data) != 2 {
+		return 0, false
+	}
+	return (int(e.Data[0]) | int(e.Data[1])<<7) - 8192, true
+}
+
+// AddEventAt adds an event to a Track, at the given tick
+func (t *Track) AddEventAt(tick uint32, event *Event) {
+	event.Tick = tick
+	t.AddEvent(event)
+}
+
+// AddEventAtTime adds an event to a Track, at the given time from the start of the song
+func (m *MIDI) AddEventAtTime(t *Track, d time.Duration, event *Event) {
+	t.AddEventAt(m.DurationToTicks(d), event)
+}
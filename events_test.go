@@ -0,0 +1,79 @@
+package midi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestChannelEventConstructors(t *testing.T) {
+	cc, err := NewControlChange(2, 7, 100)
+	if err != nil {
+		t.Fatalf("NewControlChange failed: %v", err)
+	}
+	if cc.Type != ControlChange || cc.Channel != 2 || !bytes.Equal(cc.Data, []byte{7, 100}) || cc.Size() != 3 {
+		t.Errorf("NewControlChange(2, 7, 100) = %+v", cc)
+	}
+
+	pressure, err := NewChannelPressure(1, 64)
+	if err != nil {
+		t.Fatalf("NewChannelPressure failed: %v", err)
+	}
+	if pressure.Size() != 2 {
+		t.Errorf("Size() of a channel pressure event = %d, want 2", pressure.Size())
+	}
+
+	for _, value := range []int{-8192, 0, 8191} {
+		bend, err := NewPitchBend(1, value)
+		if err != nil {
+			t.Fatalf("NewPitchBend failed: %v", err)
+		}
+		if v, ok := bend.PitchBendValue(); !ok || v != value {
+			t.Errorf("PitchBendValue() = %d, %v, want %d, true", v, ok, value)
+		}
+	}
+
+	if _, err := NewPitchBend(1, 8192); err == nil {
+		t.Error("NewPitchBend(1, 8192) should fail")
+	}
+	if _, err := NewControlChange(17, 7, 100); err == nil {
+		t.Error("NewControlChange(17, 7, 100) should fail")
+	}
+	if _, err := NewAftertouch(1, 128, 0); err == nil {
+		t.Error("NewAftertouch(1, 128, 0) should fail")
+	}
+}
+
+func TestNewSysEx(t *testing.T) {
+	sysex, err := NewSysEx([]byte{0xF0, 0x7E, 0x7F, 0x09, 0x01})
+	if err != nil {
+		t.Fatalf("NewSysEx failed: %v", err)
+	}
+	if !bytes.Equal(sysex.Data, []byte{0x7E, 0x7F, 0x09, 0x01, 0xF7}) {
+		t.Errorf("NewSysEx data = %X, want 7E 7F 09 01 F7", sysex.Data)
+	}
+	if sysex.Size() != 7 {
+		t.Errorf("Size() of a sysex event = %d, want 7", sysex.Size())
+	}
+	if _, err := NewSysEx([]byte{0x7E, 0x90, 0xF7}); err == nil {
+		t.Error("NewSysEx should fail for a data byte above 127")
+	}
+}
+
+func TestAddEventAt(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	track := NewTrack()
+	m.AddTrack(track)
+
+	cc, _ := NewControlChange(1, 64, 127)
+	track.AddEventAt(240, cc)
+	bend, _ := NewPitchBend(1, 4096)
+	m.AddEventAtTime(track, time.Second, bend)
+
+	if cc.Tick != 240 || bend.Tick != 960 {
+		t.Errorf("Events are at ticks %d and %d, want 240 and 960", cc.Tick, bend.Tick)
+	}
+	if len(track.Events) != 2 {
+		t.Errorf("Track has %d events, want 2", len(track.Events))
+	}
+}
@@ -0,0 +1,97 @@
+package midi
+
+import (
+	"fmt"
+	"sort"
+)
+
+// validateFormat checks that the format is 0, 1 or 2, and that format 0 has exactly one track
+func (m *MIDI) validateFormat() error {
+	switch m.Format {
+	case 0:
+		if len(m.Tracks) != 1 {
+			return fmt.Errorf("format 0 must have exactly one track, but has %d", len(m.Tracks))
+		}
+	case 1, 2:
+	default:
+		return fmt.Errorf("invalid MIDI file format: %d", m.Format)
+	}
+	return nil
+}
+
+// ToFormat0 merges all the tracks into one time-ordered track, and changes the format to 0
+func (m *MIDI) ToFormat0() error {
+	switch m.Format {
+	case 0:
+		if len(m.Tracks) <= 1 {
+			return nil
+		}
+	case 2:
+		return fmt.Errorf("format 2 tracks are independent sequences, and can not be merged")
+	}
+
+	var events []*Event
+	for _, t := range m.Tracks {
+		for _, e := range t.Events {
+			if !e.IsMeta(MetaEndOfTrack) {
+				events = append(events, e)
+			}
+		}
+	}
+	merged := NewTrack()
+	merged.Events = events
+	merged.SortEvents()
+
+	m.Tracks = []*Track{merged}
+	m.Format = 0
+	return nil
+}
+
+// ToFormat1 splits a format 0 track into a conductor track with the meta and
+// system exclusive events, and one track for each channel, and changes the format to 1
+func (m *MIDI) ToFormat1() error {
+	switch m.Format {
+	case 1:
+		return nil
+	case 2:
+		return fmt.Errorf("format 2 tracks are independent sequences, and can not be converted to format 1")
+	}
+	if err := m.validateFormat(); err != nil {
+		return err
+	}
+
+	conductor := NewTrack()
+	channelTracks := make(map[uint8]*Track)
+	for _, e := range m.Tracks[0].Events {
+		switch {
+		case e.IsMeta(MetaEndOfTrack):
+		case isChannelMessage(e.Type):
+			if channelTracks[e.Channel] == nil {
+				channelTracks[e.Channel] = NewTrack()
+			}
+			channelTracks[e.Channel].AddEvent(e)
+		default:
+			conductor.AddEvent(e)
+		}
+	}
+
+	channels := make([]uint8, 0, len(channelTracks))
+	for channel := range channelTracks {
+		channels = append(channels, channel)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i] < channels[j]
+	})
+
+	tracks := []*Track{conductor}
+	for _, channel := range channels {
+		t := channelTracks[channel]
+		t.SortEvents()
+		tracks = append(tracks, t)
+	}
+	conductor.SortEvents()
+
+	m.Tracks = tracks
+	m.Format = 1
+	return nil
+}
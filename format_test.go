@@ -0,0 +1,91 @@
+package midi
+
+import (
+	"bytes"
+	"testing"
+)
+
+// newFormatTestMIDI creates a format 1 song with a conductor track and two channel tracks
+func newFormatTestMIDI() *MIDI {
+	m := NewMIDI(1, 480, 120)
+	conductor := NewTrack()
+	conductor.AddEventAt(0, NewTempo(100))
+	conductor.AddEventAt(960, NewMarker("Chorus"))
+	m.AddTrack(conductor)
+	for _, channel := range []uint8{2, 1} {
+		t := NewTrack()
+		for i := uint32(0); i < 4; i++ {
+			noteOn, _ := NewNoteOn(channel, 60+channel, 100)
+			noteOff, _ := NewNoteOff(channel, 60+channel, 0)
+			t.AddEventAt(i*480, noteOn)
+			t.AddEventAt(i*480+480, noteOff)
+		}
+		t.AddEventAt(1920, NewEndOfTrack())
+		m.AddTrack(t)
+	}
+	return m
+}
+
+func TestToFormat0(t *testing.T) {
+	m := newFormatTestMIDI()
+	if err := m.ToFormat0(); err != nil {
+		t.Fatalf("ToFormat0 failed: %v", err)
+	}
+	if m.Format != 0 || len(m.Tracks) != 1 {
+		t.Fatalf("ToFormat0 gave format %d with %d tracks, want format 0 with 1 track", m.Format, len(m.Tracks))
+	}
+	events := m.Tracks[0].Events
+	if len(events) != 2+16 {
+		t.Errorf("Merged track has %d events, want 18", len(events))
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Tick < events[i-1].Tick {
+			t.Errorf("Event %d at tick %d comes after tick %d", i, events[i].Tick, events[i-1].Tick)
+		}
+		if events[i].Tick == events[i-1].Tick && events[i].IsNoteOff() && !events[i-1].IsNoteOff() {
+			t.Errorf("Note off at tick %d comes after a note on", events[i].Tick)
+		}
+	}
+	if err := m.Write(new(bytes.Buffer)); err != nil {
+		t.Errorf("Write failed: %v", err)
+	}
+}
+
+func TestToFormat1(t *testing.T) {
+	m := newFormatTestMIDI()
+	if err := m.ToFormat0(); err != nil {
+		t.Fatalf("ToFormat0 failed: %v", err)
+	}
+	if err := m.ToFormat1(); err != nil {
+		t.Fatalf("ToFormat1 failed: %v", err)
+	}
+	if m.Format != 1 || len(m.Tracks) != 3 {
+		t.Fatalf("ToFormat1 gave format %d with %d tracks, want format 1 with 3 tracks", m.Format, len(m.Tracks))
+	}
+	if len(m.Tracks[0].Events) != 2 || !m.Tracks[0].Events[0].IsMeta(MetaSetTempo) {
+		t.Errorf("The conductor track should have the tempo and the marker")
+	}
+	for i, channel := range []uint8{1, 2} {
+		for _, e := range m.Tracks[i+1].Events {
+			if e.Channel != channel {
+				t.Errorf("Track %d has an event on channel %d, want %d", i+2, e.Channel, channel)
+			}
+		}
+	}
+}
+
+func TestWriteFormatValidation(t *testing.T) {
+	m := newFormatTestMIDI()
+	m.Format = 0
+	if err := m.Write(new(bytes.Buffer)); err == nil {
+		t.Error("Write should fail for format 0 with 3 tracks")
+	}
+	m.Format = 3
+	if err := m.Write(new(bytes.Buffer)); err == nil {
+		t.Error("Write should fail for format 3")
+	}
+	m.Format = 2
+	if err := m.ToFormat0(); err == nil {
+		t.Error("ToFormat0 should fail for format 2")
+	}
+}
@@ -1,18 +1,23 @@
 package midi
 
-import "math"
+import (
+	"fmt"
+	"math"
+)
 
+// FrequencyToMidi converts a frequency to the nearest MIDI note, and the offset
+// from that note as a pitch bend, where 8192 is one semitone
 func FrequencyToMidi(frequency float64) (note uint8, bend int) {
 	const A4 = 440.0
 	const A4MidiNote = 69
 
-	midi := math.Log2(frequency/A4)*12 + A4MidiNote
-
-	// Calculate the pitch bend value based on the fraction part of the midi number
-	bend = int(math.Round((midi - math.Floor(midi)) * 8192)) // 8192 is the range for pitch bend (-8192 to 8191)
+	exact := math.Log2(frequency/A4)*12 + A4MidiNote
 
 	// Round up if the fractional part is 0.5 or more
-	midi = math.Floor(midi + 0.5)
+	midi := math.Floor(exact + 0.5)
+
+	// Calculate the pitch bend from the rounded note, where 8192 is one semitone
+	bend = int(math.Round((exact - midi) * 8192))
 
 	// Limit the midi note to valid range
 	if midi < 0 {
@@ -24,39 +29,47 @@ func FrequencyToMidi(frequency float64) (note uint8, bend int) {
 	return uint8(midi), bend
 }
 
+// NoteNameToFrequency converts a note name like "A4" or "C#3" to a frequency,
+// or returns 0 if the note name is invalid
 func NoteNameToFrequency(note string) float64 {
-	noteNameToPitch := map[string]float64{
-		"C":  0,
-		"C#": 1,
-		"Db": 1,
-		"D":  2,
-		"D#": 3,
-		"Eb": 3,
-		"E":  4,
-		"F":  5,
-		"F#": 6,
-		"Gb": 6,
-		"G":  7,
-		"G#": 8,
-		"Ab": 8,
-		"A":  9,
-		"A#": 10,
-		"Bb": 10,
-		"B":  11,
-		"H":  11,
+	p, err := ParseNoteName(note)
+	if err != nil {
+		return 0
 	}
+	return midiNumberToFrequency(float64(p.Number))
+}
 
-	if len(note) < 2 || len(note) > 3 {
-		return 0
+// FrequencyToMidiIn converts a frequency to the nearest MIDI note in a tuning, and the
+// offset from the frequency of that note as a pitch bend, where 8192 is one semitone.
+// If the tuning is nil, A4 is 440Hz with equal temperament. Unlike FrequencyToMidi,
+// an error is returned if the frequency is not above 0 or has no MIDI note near it.
+func FrequencyToMidiIn(frequency float64, tuning Tuning) (note uint8, bend int, err error) {
+	if frequency <= 0 || math.IsInf(frequency, 0) || math.IsNaN(frequency) {
+		return 0, 0, fmt.Errorf("invalid frequency %g", frequency)
 	}
+	p := FrequencyToPitch(frequency, tuning)
+	if p.Number < 0 || p.Number > 127 || (tuning != nil && tuning.Frequency(p.Number) <= 0) {
+		return 0, 0, fmt.Errorf("the frequency %gHz is outside of the MIDI note range", frequency)
+	}
+	return uint8(p.Number), int(math.Round(p.Cents / 100 * 8192)), nil
+}
 
-	noteName := note[:len(note)-1]
-	pitch, valid := noteNameToPitch[noteName]
-	if !valid {
-		return 0
+// NoteNameToFrequencyIn converts a note name like "A4" or "C#3" to a frequency in
+// a tuning. An error is returned if the note name is invalid, or if the tuning has
+// no frequency for the note, like a key that is not mapped in a Scala .kbm file.
+func NoteNameToFrequencyIn(note string, tuning Tuning) (float64, error) {
+	p, err := ParseNoteName(note)
+	if err != nil {
+		return 0, err
 	}
+	frequency := p.Frequency(tuning)
+	if frequency <= 0 {
+		return 0, fmt.Errorf("note %s has no frequency in the tuning", p)
+	}
+	return frequency, nil
+}
 
-	octave := float64(note[len(note)-1] - '0')
-	midiNumber := 12*(octave+1) + pitch
-	return 440 * math.Pow(2, (midiNumber-69)/12)
+// midiNumberToFrequency converts a MIDI note number to a frequency, with A4 at 440Hz
+func midiNumberToFrequency(number float64) float64 {
+	return 440 * math.Pow(2, (number-69)/12)
 }
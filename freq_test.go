@@ -44,3 +44,29 @@ func TestNoteNameToFrequency(t *testing.T) {
 		t.Errorf("Expected frequency for D4 is 293.66Hz, but got %f", frequency)
 	}
 }
+
+func TestFrequencyToMidiIn(t *testing.T) {
+	note, bend, err := FrequencyToMidiIn(432, EqualTemperament{A4: 432})
+	if err != nil || note != 69 || bend != 0 {
+		t.Errorf("FrequencyToMidiIn(432, A4 at 432Hz) = %d, %d, %v, want 69, 0, nil", note, bend, err)
+	}
+	note, bend, err = FrequencyToMidiIn(440, EqualTemperament{A4: 432})
+	if err != nil || note != 69 || bend <= 0 {
+		t.Errorf("FrequencyToMidiIn(440, A4 at 432Hz) = %d, %d, %v, want 69 with an upward bend", note, bend, err)
+	}
+	for _, frequency := range []float64{0, -440, 20000} {
+		if _, _, err := FrequencyToMidiIn(frequency, nil); err == nil {
+			t.Errorf("FrequencyToMidiIn(%g, nil) should fail", frequency)
+		}
+	}
+}
+
+func TestNoteNameToFrequencyIn(t *testing.T) {
+	frequency, err := NoteNameToFrequencyIn("A4", EqualTemperament{A4: 415})
+	if err != nil || frequency != 415 {
+		t.Errorf("NoteNameToFrequencyIn(\"A4\", A4 at 415Hz) = %f, %v, want 415, nil", frequency, err)
+	}
+	if _, err := NoteNameToFrequencyIn("X4", nil); err == nil {
+		t.Errorf("NoteNameToFrequencyIn should fail for an invalid note name")
+	}
+}
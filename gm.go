@@ -0,0 +1,357 @@
+package midi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DrumChannel is the General MIDI percussion channel
+const DrumChannel = 10
+
+// GM2MelodyBank is the bank select MSB of the General MIDI Level 2 melodic sounds.
+// The variation of a program is the bank select LSB.
+const GM2MelodyBank = 121
+
+// The bank select controllers
+const (
+	controllerBankSelectMSB = 0
+	controllerBankSelectLSB = 32
+)
+
+// gmPrograms are the General MIDI program names. The program numbers start
+// at 0, like in program change events.
+var gmPrograms = [128]string{
+	// Piano
+	"Acoustic Grand Piano", "Bright Acoustic Piano", "Electric Grand Piano", "Honky-tonk Piano",
+	"Electric Piano 1", "Electric Piano 2", "Harpsichord", "Clavi",
+	// Chromatic Percussion
+	"Celesta", "Glockenspiel", "Music Box", "Vibraphone",
+	"Marimba", "Xylophone", "Tubular Bells", "Dulcimer",
+	// Organ
+	"Drawbar Organ", "Percussive Organ", "Rock Organ", "Church Organ",
+	"Reed Organ", "Accordion", "Harmonica", "Tango Accordion",
+	// Guitar
+	"Acoustic Guitar (nylon)", "Acoustic Guitar (steel)", "Electric Guitar (jazz)", "Electric Guitar (clean)",
+	"Electric Guitar (muted)", "Overdriven Guitar", "Distortion Guitar", "Guitar harmonics",
+	// Bass
+	"Acoustic Bass", "Electric Bass (finger)", "Electric Bass (pick)", "Fretless Bass",
+	"Slap Bass 1", "Slap Bass 2", "Synth Bass 1", "Synth Bass 2",
+	// Strings
+	"Violin", "Viola", "Cello", "Contrabass",
+	"Tremolo Strings", "Pizzicato Strings", "Orchestral Harp", "Timpani",
+	// Ensemble
+	"String Ensemble 1", "String Ensemble 2", "SynthStrings 1", "SynthStrings 2",
+	"Choir Aahs", "Voice Oohs", "Synth Voice", "Orchestra Hit",
+	// Brass
+	"Trumpet", "Trombone", "Tuba", "Muted Trumpet",
+	"French Horn", "Brass Section", "SynthBrass 1", "SynthBrass 2",
+	// Reed
+	"Soprano Sax", "Alto Sax", "Tenor Sax", "Baritone Sax",
+	"Oboe", "English Horn", "Bassoon", "Clarinet",
+	// Pipe
+	"Piccolo", "Flute", "Recorder", "Pan Flute",
+	"Blown Bottle", "Shakuhachi", "Whistle", "Ocarina",
+	// Synth Lead
+	"Lead 1 (square)", "Lead 2 (sawtooth)", "Lead 3 (calliope)", "Lead 4 (chiff)",
+	"Lead 5 (charang)", "Lead 6 (voice)", "Lead 7 (fifths)", "Lead 8 (bass + lead)",
+	// Synth Pad
+	"Pad 1 (new age)", "Pad 2 (warm)", "Pad 3 (polysynth)", "Pad 4 (choir)",
+	"Pad 5 (bowed)", "Pad 6 (metallic)", "Pad 7 (halo)", "Pad 8 (sweep)",
+	// Synth Effects
+	"FX 1 (rain)", "FX 2 (soundtrack)", "FX 3 (crystal)", "FX 4 (atmosphere)",
+	"FX 5 (brightness)", "FX 6 (goblins)", "FX 7 (echoes)", "FX 8 (sci-fi)",
+	// Ethnic
+	"Sitar", "Banjo", "Shamisen", "Koto",
+	"Kalimba", "Bag pipe", "Fiddle", "Shanai",
+	// Percussive
+	"Tinkle Bell", "Agogo", "Steel Drums", "Woodblock",
+	"Taiko Drum", "Melodic Tom", "Synth Drum", "Reverse Cymbal",
+	// Sound Effects
+	"Guitar Fret Noise", "Breath Noise", "Seashore", "Bird Tweet",
+	"Telephone Ring", "Helicopter", "Applause", "Gunshot",
+}
+
+// gm2Variations are the names of the General MIDI Level 2 variations of the programs,
+// where the first name is variation 1. Variation 0 is the GM1 program.
+var gm2Variations = map[uint8][]string{
+	0:   {"Wide Acoustic Grand", "Dark Acoustic Grand"},
+	1:   {"Wide Bright Acoustic"},
+	2:   {"Wide Electric Grand"},
+	3:   {"Wide Honky-tonk"},
+	4:   {"Detuned Electric Piano 1", "Electric Piano 1 Variation", "60's Electric Piano"},
+	5:   {"Detuned Electric Piano 2", "Electric Piano 2 Variation", "Electric Piano Legend", "Electric Piano Phase"},
+	6:   {"Coupled Harpsichord", "Wide Harpsichord", "Open Harpsichord"},
+	7:   {"Pulse Clavinet"},
+	11:  {"Wet Vibraphone"},
+	12:  {"Wide Marimba"},
+	14:  {"Church Bells", "Carillon"},
+	16:  {"Detuned Tonewheel Organ", "60's Electric Organ", "Organ 4"},
+	17:  {"Detuned Percussive Organ", "Organ 5"},
+	19:  {"Church Organ 2", "Church Organ 3"},
+	20:  {"Puff Organ"},
+	21:  {"Italian Accordion"},
+	24:  {"Ukulele", "Nylon Guitar Key Off", "Nylon Guitar 2"},
+	25:  {"12-String Guitar", "Mandolin", "Steel Guitar with Body Sound"},
+	26:  {"Pedal Steel Guitar"},
+	27:  {"Detuned Clean Electric Guitar", "Mid Tone Guitar"},
+	28:  {"Funk Guitar", "Funk Guitar 2", "Jazz Man"},
+	29:  {"Guitar Pinch"},
+	30:  {"Distortion Guitar with Feedback", "Distorted Rhythm Guitar"},
+	31:  {"Guitar Feedback"},
+	33:  {"Finger Slap Bass"},
+	38:  {"Synth Bass 101", "Synth Bass 3 (Resonance)", "Clavi Bass", "Hammer"},
+	39:  {"Synth Bass 4 (Attack)", "Synth Bass (Rubber)", "Attack Pulse"},
+	40:  {"Slow Violin"},
+	46:  {"Yang Qin"},
+	48:  {"Strings and Brass", "60s Strings"},
+	50:  {"Synth Strings 3"},
+	52:  {"Choir Aahs 2"},
+	53:  {"Humming"},
+	54:  {"Analog Voice"},
+	55:  {"Bass Hit", "6th Hit", "Euro Hit"},
+	56:  {"Dark Trumpet Soft"},
+	57:  {"Trombone 2", "Bright Trombone"},
+	59:  {"Muted Trumpet 2"},
+	60:  {"French Horn 2 (warm)"},
+	61:  {"Brass Section 2 (octave mix)"},
+	62:  {"Synth Brass 3", "Analog Synth Brass 1", "Jump Brass"},
+	63:  {"Synth Brass 4", "Analog Synth Brass 2"},
+	80:  {"Lead 1a (square 2)", "Lead 1b (sine)"},
+	81:  {"Lead 2a (sawtooth 2)", "Lead 2b (saw + pulse)", "Lead 2c (double sawtooth)", "Lead 2d (sequenced analog)"},
+	84:  {"Lead 5a (wire lead)"},
+	87:  {"Lead 8a (soft wrl)"},
+	89:  {"Pad 2a (sine pad)"},
+	91:  {"Pad 4a (itopia)"},
+	98:  {"FX 3a (synth mallet)"},
+	102: {"FX 7a (echo bell)", "FX 7b (echo pan)"},
+	104: {"Sitar 2 (bend)"},
+	107: {"Taisho Koto"},
+	115: {"Castanets"},
+	116: {"Concert Bass Drum"},
+	117: {"Melodic Tom 2 (power)"},
+	118: {"Rhythm Box Tom", "Electric Drum"},
+	120: {"Guitar Cutting Noise", "Acoustic Bass String Slap"},
+	121: {"Flute Key Click"},
+	122: {"Rain", "Thunder", "Wind", "Stream", "Bubble"},
+	123: {"Dog", "Horse Gallop", "Bird Tweet 2"},
+	124: {"Telephone Ring 2", "Door Creaking", "Door", "Scratch", "Wind Chime"},
+	125: {"Car Engine", "Car Stop", "Car Pass", "Car Crash", "Siren", "Train", "Jetplane", "Starship", "Burst Noise"},
+	126: {"Laughing", "Screaming", "Punch", "Heart Beat", "Footsteps"},
+	127: {"Machine Gun", "Lasergun", "Explosion"},
+}
+
+// gmFamilies are the General MIDI program families, with 8 programs in each
+var gmFamilies = [16]string{
+	"Piano", "Chromatic Percussion", "Organ", "Guitar",
+	"Bass", "Strings", "Ensemble", "Brass",
+	"Reed", "Pipe", "Synth Lead", "Synth Pad",
+	"Synth Effects", "Ethnic", "Percussive", "Sound Effects",
+}
+
+// gmDrums are the General MIDI percussion key names, for the drum channel.
+// Keys 35 to 81 are from GM1, the rest are GM2 additions.
+var gmDrums = map[uint8]string{
+	27: "High Q",
+	28: "Slap",
+	29: "Scratch Push",
+	30: "Scratch Pull",
+	31: "Sticks",
+	32: "Square Click",
+	33: "Metronome Click",
+	34: "Metronome Bell",
+	35: "Acoustic Bass Drum",
+	36: "Bass Drum 1",
+	37: "Side Stick",
+	38: "Acoustic Snare",
+	39: "Hand Clap",
+	40: "Electric Snare",
+	41: "Low Floor Tom",
+	42: "Closed Hi-Hat",
+	43: "High Floor Tom",
+	44: "Pedal Hi-Hat",
+	45: "Low Tom",
+	46: "Open Hi-Hat",
+	47: "Low-Mid Tom",
+	48: "Hi-Mid Tom",
+	49: "Crash Cymbal 1",
+	50: "High Tom",
+	51: "Ride Cymbal 1",
+	52: "Chinese Cymbal",
+	53: "Ride Bell",
+	54: "Tambourine",
+	55: "Splash Cymbal",
+	56: "Cowbell",
+	57: "Crash Cymbal 2",
+	58: "Vibraslap",
+	59: "Ride Cymbal 2",
+	60: "Hi Bongo",
+	61: "Low Bongo",
+	62: "Mute Hi Conga",
+	63: "Open Hi Conga",
+	64: "Low Conga",
+	65: "High Timbale",
+	66: "Low Timbale",
+	67: "High Agogo",
+	68: "Low Agogo",
+	69: "Cabasa",
+	70: "Maracas",
+	71: "Short Whistle",
+	72: "Long Whistle",
+	73: "Short Guiro",
+	74: "Long Guiro",
+	75: "Claves",
+	76: "Hi Wood Block",
+	77: "Low Wood Block",
+	78: "Mute Cuica",
+	79: "Open Cuica",
+	80: "Mute Triangle",
+	81: "Open Triangle",
+	82: "Shaker",
+	83: "Jingle Bell",
+	84: "Belltree",
+	85: "Castanets",
+	86: "Mute Surdo",
+	87: "Open Surdo",
+}
+
+// gmControllers are the General MIDI (GM1 and GM2) controller names
+var gmControllers = map[uint8]string{
+	0:   "Bank Select",
+	1:   "Modulation",
+	2:   "Breath Controller",
+	4:   "Foot Controller",
+	5:   "Portamento Time",
+	6:   "Data Entry",
+	7:   "Channel Volume",
+	8:   "Balance",
+	10:  "Pan",
+	11:  "Expression",
+	12:  "Effect Control 1",
+	13:  "Effect Control 2",
+	32:  "Bank Select LSB",
+	33:  "Modulation LSB",
+	38:  "Data Entry LSB",
+	64:  "Sustain",
+	65:  "Portamento",
+	66:  "Sostenuto",
+	67:  "Soft Pedal",
+	68:  "Legato Footswitch",
+	69:  "Hold 2",
+	70:  "Sound Variation",
+	71:  "Timbre/Harmonic Intensity",
+	72:  "Release Time",
+	73:  "Attack Time",
+	74:  "Brightness",
+	75:  "Decay Time",
+	76:  "Vibrato Rate",
+	77:  "Vibrato Depth",
+	78:  "Vibrato Delay",
+	84:  "Portamento Control",
+	91:  "Reverb Send Level",
+	92:  "Tremolo Depth",
+	93:  "Chorus Send Level",
+	94:  "Celeste Depth",
+	95:  "Phaser Depth",
+	96:  "Data Increment",
+	97:  "Data Decrement",
+	98:  "NRPN LSB",
+	99:  "NRPN MSB",
+	100: "RPN LSB",
+	101: "RPN MSB",
+	120: "All Sound Off",
+	121: "Reset All Controllers",
+	122: "Local Control",
+	123: "All Notes Off",
+	124: "Omni Mode Off",
+	125: "Omni Mode On",
+	126: "Mono Mode On",
+	127: "Poly Mode On",
+}
+
+// ProgramName returns the General MIDI name of a program, from 0 to 127
+func ProgramName(program uint8) string {
+	if program > 127 {
+		return ""
+	}
+	return gmPrograms[program]
+}
+
+// ProgramFamily returns the General MIDI family of a program, like "Piano" or "Brass"
+func ProgramFamily(program uint8) string {
+	if program > 127 {
+		return ""
+	}
+	return gmFamilies[program/8]
+}
+
+// ProgramByName returns the program number of a GM1 instrument name.
+// The name is not case sensitive.
+func ProgramByName(name string) (uint8, error) {
+	for program, programName := range gmPrograms {
+		if strings.EqualFold(programName, name) {
+			return uint8(program), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown General MIDI instrument: %q", name)
+}
+
+// ProgramVariationName returns the name of a GM2 variation of a program, or the
+// GM1 name of the program for variation 0
+func ProgramVariationName(program, variation uint8) string {
+	if variation == 0 {
+		return ProgramName(program)
+	}
+	names := gm2Variations[program]
+	if int(variation) > len(names) {
+		return ""
+	}
+	return names[variation-1]
+}
+
+// ProgramVariationByName returns the program number and the GM2 variation of a GM1
+// or GM2 instrument name, where GM1 names are variation 0. The name is not case sensitive.
+func ProgramVariationByName(name string) (program, variation uint8, err error) {
+	if program, err := ProgramByName(name); err == nil {
+		return program, 0, nil
+	}
+	for program, names := range gm2Variations {
+		for i, variationName := range names {
+			if strings.EqualFold(variationName, name) {
+				return program, uint8(i + 1), nil
+			}
+		}
+	}
+	return 0, 0, fmt.Errorf("unknown General MIDI instrument: %q", name)
+}
+
+// DrumName returns the General MIDI name of a percussion key
+func DrumName(key uint8) string {
+	return gmDrums[key]
+}
+
+// DrumKey returns the key of a General MIDI percussion name, like "Closed Hi-Hat".
+// The name is not case sensitive.
+func DrumKey(name string) (uint8, error) {
+	for key, drumName := range gmDrums {
+		if strings.EqualFold(drumName, name) {
+			return key, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown General MIDI percussion name: %q", name)
+}
+
+// ControllerName returns the General MIDI name of a controller number
+func ControllerName(controller uint8) string {
+	return gmControllers[controller]
+}
+
+// ControllerByName returns the controller number of a General MIDI controller name.
+// The name is not case sensitive.
+func ControllerByName(name string) (uint8, error) {
+	for controller, controllerName := range gmControllers {
+		if strings.EqualFold(controllerName, name) {
+			return controller, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown General MIDI controller: %q", name)
+}
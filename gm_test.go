@@ -0,0 +1,149 @@
+package midi
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgramNames(t *testing.T) {
+	program, err := ProgramByName("Acoustic Grand Piano")
+	if err != nil || program != 0 {
+		t.Errorf("ProgramByName(\"Acoustic Grand Piano\") = %d, %v, want 0, nil", program, err)
+	}
+	program, err = ProgramByName("trumpet")
+	if err != nil || program != 56 {
+		t.Errorf("ProgramByName(\"trumpet\") = %d, %v, want 56, nil", program, err)
+	}
+	if _, err := ProgramByName("Kazoo"); err == nil {
+		t.Error("ProgramByName(\"Kazoo\") should fail")
+	}
+	if name := ProgramName(127); name != "Gunshot" {
+		t.Errorf("ProgramName(127) = %q, want \"Gunshot\"", name)
+	}
+	if family := ProgramFamily(56); family != "Brass" {
+		t.Errorf("ProgramFamily(56) = %q, want \"Brass\"", family)
+	}
+}
+
+func TestDrumAndControllerNames(t *testing.T) {
+	key, err := DrumKey("Closed Hi-Hat")
+	if err != nil || key != 42 {
+		t.Errorf("DrumKey(\"Closed Hi-Hat\") = %d, %v, want 42, nil", key, err)
+	}
+	if name := DrumName(36); name != "Bass Drum 1" {
+		t.Errorf("DrumName(36) = %q, want \"Bass Drum 1\"", name)
+	}
+	controller, err := ControllerByName("Sustain")
+	if err != nil || controller != 64 {
+		t.Errorf("ControllerByName(\"Sustain\") = %d, %v, want 64, nil", controller, err)
+	}
+	if name := ControllerName(7); name != "Channel Volume" {
+		t.Errorf("ControllerName(7) = %q, want \"Channel Volume\"", name)
+	}
+}
+
+func TestAddNoteByName(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	track := NewTrack()
+	m.AddTrack(track)
+
+	if err := m.AddNote(track, &Note{Frequency: 440, Duration: time.Second, Channel: 1, Instrument: "Violin"}); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	if err := m.AddNote(track, &Note{Duration: time.Second, Channel: 1, Drum: "Acoustic Snare"}); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	if err := m.AddNote(track, &Note{Duration: time.Second, Channel: 1, Drum: "Kazoo"}); err == nil {
+		t.Error("AddNote should fail for an unknown drum")
+	}
+
+	var program, drum *Event
+	for _, e := range track.Events {
+		switch {
+		case e.Type == EventProgramChange && e.Channel == 1:
+			program = e
+		case e.Type == EventNoteOn && e.Channel == DrumChannel:
+			drum = e
+		}
+	}
+	if program == nil || program.Data[0] != 40 {
+		t.Error("Expected a program change to Violin (40) on channel 1")
+	}
+	if drum == nil || drum.Data[0] != 38 {
+		t.Error("Expected an Acoustic Snare (38) note on the drum channel")
+	}
+}
+
+func TestProgramVariations(t *testing.T) {
+	program, variation, err := ProgramVariationByName("Mandolin")
+	if err != nil || program != 25 || variation != 2 {
+		t.Errorf("ProgramVariationByName(\"Mandolin\") = %d, %d, %v, want 25, 2, nil", program, variation, err)
+	}
+	program, variation, err = ProgramVariationByName("violin")
+	if err != nil || program != 40 || variation != 0 {
+		t.Errorf("ProgramVariationByName(\"violin\") = %d, %d, %v, want 40, 0, nil", program, variation, err)
+	}
+	if _, _, err := ProgramVariationByName("Kazoo"); err == nil {
+		t.Error("ProgramVariationByName(\"Kazoo\") should fail")
+	}
+	if name := ProgramVariationName(0, 2); name != "Dark Acoustic Grand" {
+		t.Errorf("ProgramVariationName(0, 2) = %q, want \"Dark Acoustic Grand\"", name)
+	}
+	if name := ProgramVariationName(56, 0); name != "Trumpet" {
+		t.Errorf("ProgramVariationName(56, 0) = %q, want \"Trumpet\"", name)
+	}
+	if name := ProgramVariationName(0, 3); name != "" {
+		t.Errorf("ProgramVariationName(0, 3) = %q, want \"\"", name)
+	}
+
+	// The names of the variations must not collide with each other or with the GM1 names
+	seen := make(map[string]bool)
+	for _, name := range gmPrograms {
+		seen[strings.ToLower(name)] = true
+	}
+	for program, names := range gm2Variations {
+		for _, name := range names {
+			if seen[strings.ToLower(name)] {
+				t.Errorf("the name %q of a variation of program %d is not unique", name, program)
+			}
+			seen[strings.ToLower(name)] = true
+		}
+	}
+}
+
+func TestAddNoteVariation(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	track := NewTrack()
+	m.AddTrack(track)
+
+	// A GM1 note does not select a bank
+	if err := m.AddNote(track, &Note{Frequency: 440, Duration: time.Second, Channel: 1, Instrument: "Acoustic Guitar (steel)"}); err != nil {
+		t.Fatal(err)
+	}
+	// A GM2 variation of the same program selects the bank and repeats the program change
+	if err := m.AddNote(track, &Note{Frequency: 440, Duration: time.Second, Channel: 1, Instrument: "Mandolin", EventDelay: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	// The same variation again does not select the bank again
+	if err := m.AddNote(track, &Note{Frequency: 440, Duration: time.Second, Channel: 1, Program: 25, Variation: 2, EventDelay: 2 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+
+	var banks [][]byte
+	var programs []uint32
+	for _, e := range track.Events {
+		switch e.Type {
+		case ControlChange:
+			banks = append(banks, e.Data)
+		case ProgramChange:
+			programs = append(programs, e.Tick)
+		}
+	}
+	if len(banks) != 2 || banks[0][0] != 0 || banks[0][1] != GM2MelodyBank || banks[1][0] != 32 || banks[1][1] != 2 {
+		t.Errorf("got the bank select controllers %v, want [[0 121] [32 2]]", banks)
+	}
+	if len(programs) != 2 || programs[0] != 0 || programs[1] != 960 {
+		t.Errorf("got program changes at the ticks %v, want [0 960]", programs)
+	}
+}
@@ -0,0 +1,86 @@
+package midi
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Groove is a groove template: the timing and the velocity of each position in a
+// pattern of grid positions, like the sixteenth notes in a bar
+type Groove struct {
+	Grid       uint32    // The length of a grid position, in ticks
+	Offsets    []float64 // The offset of the notes at each position, in ticks
+	Velocities []float64 // The velocity of the notes at each position, relative to the average velocity
+}
+
+// Humanize moves the start of each note in a track by a random number of ticks,
+// up to the given timing, and changes the velocity by up to the given velocity.
+// The same seed gives the same result. The notes keep their lengths, and the
+// velocities stay from 1 to 127.
+func (t *Track) Humanize(timing uint32, velocity uint8, seed int64) {
+	r := rand.New(rand.NewSource(seed))
+	t.moveNotes(func(pair *notePair) (int64, int64, bool) {
+		shift := r.Int63n(2*int64(timing)+1) - int64(timing)
+		v := int(pair.on.Data[1]) + r.Intn(2*int(velocity)+1) - int(velocity)
+		pair.on.Data[1] = clampVelocity(float64(v))
+		return int64(pair.on.Tick) + shift, int64(pair.end) + shift, true
+	})
+}
+
+// ExtractGroove creates a groove template from the notes in a track, with the
+// average timing and velocity of the notes at each of the given number of grid positions
+func (t *Track) ExtractGroove(grid uint32, steps int) *Groove {
+	g := &Groove{Grid: grid, Offsets: make([]float64, steps), Velocities: make([]float64, steps)}
+	if grid == 0 || steps <= 0 {
+		return g
+	}
+	counts := make([]int, steps)
+	var velocitySum float64
+	pairs := notePairs(t.Events)
+	for _, pair := range pairs {
+		position, step := g.position(pair.on.Tick)
+		g.Offsets[step] += float64(int64(pair.on.Tick) - position)
+		g.Velocities[step] += float64(pair.on.Data[1])
+		velocitySum += float64(pair.on.Data[1])
+		counts[step]++
+	}
+	average := velocitySum / float64(len(pairs))
+	for step, count := range counts {
+		if count == 0 {
+			g.Velocities[step] = 1
+			continue
+		}
+		g.Offsets[step] /= float64(count)
+		g.Velocities[step] /= float64(count) * average
+	}
+	return g
+}
+
+// ApplyGroove moves the notes in a track towards the timing of a groove template,
+// and scales their velocities, where the strength is from 0 to 1. The notes keep
+// their lengths.
+func (t *Track) ApplyGroove(g *Groove, strength float64) {
+	if g.Grid == 0 || len(g.Offsets) == 0 || len(g.Offsets) != len(g.Velocities) {
+		return
+	}
+	strength = math.Max(0, math.Min(1, strength))
+	t.moveNotes(func(pair *notePair) (int64, int64, bool) {
+		position, step := g.position(pair.on.Tick)
+		target := float64(position) + g.Offsets[step]
+		shift := int64(math.Round((target - float64(pair.on.Tick)) * strength))
+		scale := 1 + (g.Velocities[step]-1)*strength
+		pair.on.Data[1] = clampVelocity(float64(pair.on.Data[1]) * scale)
+		return int64(pair.on.Tick) + shift, int64(pair.end) + shift, true
+	})
+}
+
+// position returns the grid position that is closest to a tick, and its step in the groove
+func (g *Groove) position(tick uint32) (int64, int) {
+	index := int64(math.Round(float64(tick) / float64(g.Grid)))
+	return index * int64(g.Grid), int(index % int64(len(g.Offsets)))
+}
+
+// clampVelocity rounds a velocity, and keeps it from 1 to 127, so that a "note on" event stays a "note on" event
+func clampVelocity(velocity float64) uint8 {
+	return uint8(math.Max(1, math.Min(127, math.Round(velocity))))
+}
@@ -0,0 +1,70 @@
+package midi
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHumanize(t *testing.T) {
+	ticks := []uint32{96, 144, 192, 240, 288, 336, 384, 432}
+	humanized := func(seed int64) []*Note {
+		track := noteTrack(t, ticks...)
+		track.Humanize(10, 20, seed)
+		return track.Notes()
+	}
+	a, b := humanized(1), humanized(1)
+	moved := false
+	for i, note := range a {
+		original := ticks[2*i]
+		if note.Tick != b[i].Tick || note.Velocity != b[i].Velocity {
+			t.Fatalf("the same seed should give the same result")
+		}
+		if math.Abs(float64(note.Tick)-float64(original)) > 10 {
+			t.Errorf("note %d moved from %d to %d, more than 10 ticks", i, original, note.Tick)
+		}
+		if note.DurationTicks != 48 {
+			t.Errorf("note %d has length %d, want 48", i, note.DurationTicks)
+		}
+		if note.Velocity < 80 || note.Velocity > 120 {
+			t.Errorf("note %d has velocity %d, want from 80 to 120", i, note.Velocity)
+		}
+		moved = moved || note.Tick != original
+	}
+	if !moved {
+		t.Errorf("no notes were moved")
+	}
+}
+
+func TestGroove(t *testing.T) {
+	// A pattern of two eighth notes, where the second one is late and soft
+	source := noteTrack(t, 0, 40, 58, 90, 96, 130, 154, 190)
+	for i, e := range source.Events {
+		if e.Type == NoteOn && e.Tick%96 != 0 {
+			source.Events[i].Data[1] = 50
+		}
+	}
+	g := source.ExtractGroove(48, 2)
+	if g.Offsets[0] != 0 || g.Offsets[1] != 10 {
+		t.Errorf("offsets = %v, want [0 10]", g.Offsets)
+	}
+	if math.Abs(g.Velocities[0]-100.0/75) > 1e-9 || math.Abs(g.Velocities[1]-50.0/75) > 1e-9 {
+		t.Errorf("velocities = %v, want [1.33 0.67]", g.Velocities)
+	}
+
+	target := noteTrack(t, 192, 220, 240, 270)
+	target.ApplyGroove(g, 1)
+	notes := target.Notes()
+	if notes[0].Tick != 192 || notes[1].Tick != 250 || notes[1].DurationTicks != 30 {
+		t.Errorf("notes start at %d and %d, want 192 and 250", notes[0].Tick, notes[1].Tick)
+	}
+	if notes[0].Velocity != 127 || notes[1].Velocity != 67 {
+		t.Errorf("velocities = %d and %d, want 127 and 67", notes[0].Velocity, notes[1].Velocity)
+	}
+
+	// Half the strength moves the notes half the way
+	target = noteTrack(t, 240, 270)
+	target.ApplyGroove(g, 0.5)
+	if notes := target.Notes(); notes[0].Tick != 245 || notes[0].Velocity != 83 {
+		t.Errorf("note starts at %d with velocity %d, want 245 and 83", notes[0].Tick, notes[0].Velocity)
+	}
+}
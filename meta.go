@@ -0,0 +1,199 @@
+package midi
+
+import (
+	"fmt"
+	"math"
+)
+
+// Meta event types, stored in Event.Meta when Event.Type is EventMeta
+const (
+	MetaSequenceNumber    = 0x00
+	MetaText              = 0x01
+	MetaCopyright         = 0x02
+	MetaTrackName         = 0x03
+	MetaInstrumentName    = 0x04
+	MetaLyric             = 0x05
+	MetaMarker            = 0x06
+	MetaCuePoint          = 0x07
+	MetaChannelPrefix     = 0x20
+	MetaEndOfTrack        = 0x2F
+	MetaSetTempo          = 0x51
+	MetaSMPTEOffset       = 0x54
+	MetaTimeSignature     = 0x58
+	MetaKeySignature      = 0x59
+	MetaSequencerSpecific = 0x7F
+)
+
+// NewMetaEvent creates a new meta event of the given type
+func NewMetaEvent(metaType uint8, data []byte) *Event {
+	return &Event{
+		Type: EventMeta,
+		Meta: metaType,
+		Data: data,
+	}
+}
+
+// NewTempo creates a new Set Tempo meta event
+func NewTempo(bpm float64) *Event {
+	microseconds := uint32(math.Round(60000000.0 / bpm))
+	return NewMetaEvent(MetaSetTempo, []byte{byte(microseconds >> 16), byte(microseconds >> 8), byte(microseconds)})
+}
+
+// NewTimeSignature creates a new Time Signature meta event, like 3/4 or 6/8.
+// The denominator must be a power of two.
+func NewTimeSignature(numerator, denominator uint8) (*Event, error) {
+	if numerator == 0 {
+		return nil, fmt.Errorf("invalid time signature numerator: %d", numerator)
+	}
+	power := uint8(0)
+	for d := denominator; d > 1; d >>= 1 {
+		if d&1 != 0 {
+			return nil, fmt.Errorf("time signature denominator is not a power of two: %d", denominator)
+		}
+		power++
+	}
+	if denominator == 0 {
+		return nil, fmt.Errorf("invalid time signature denominator: %d", denominator)
+	}
+	// 24 MIDI clocks per metronome click and 8 notated 32nd notes per quarter note are the usual values
+	return NewMetaEvent(MetaTimeSignature, []byte{numerator, power, 24, 8}), nil
+}
+
+// NewKeySignature creates a new Key Signature meta event. The key is given as
+// the number of sharps (positive) or flats (negative), from -7 to 7.
+func NewKeySignature(sharps int8, minor bool) (*Event, error) {
+	if sharps < -7 || sharps > 7 {
+		return nil, fmt.Errorf("invalid number of sharps or flats in key signature: %d", sharps)
+	}
+	var mode byte
+	if minor {
+		mode = 1
+	}
+	return NewMetaEvent(MetaKeySignature, []byte{byte(sharps), mode}), nil
+}
+
+// NewText creates a new Text meta event
+func NewText(text string) *Event {
+	return NewMetaEvent(MetaText, []byte(text))
+}
+
+// NewCopyright creates a new Copyright Notice meta event
+func NewCopyright(text string) *Event {
+	return NewMetaEvent(MetaCopyright, []byte(text))
+}
+
+// NewTrackName creates a new Sequence/Track Name meta event
+func NewTrackName(name string) *Event {
+	return NewMetaEvent(MetaTrackName, []byte(name))
+}
+
+// NewInstrumentName creates a new Instrument Name meta event
+func NewInstrumentName(name string) *Event {
+	return NewMetaEvent(MetaInstrumentName, []byte(name))
+}
+
+// NewLyric creates a new Lyric meta event
+func NewLyric(text string) *Event {
+	return NewMetaEvent(MetaLyric, []byte(text))
+}
+
+// NewMarker creates a new Marker meta event
+func NewMarker(text string) *Event {
+	return NewMetaEvent(MetaMarker, []byte(text))
+}
+
+// NewCuePoint creates a new Cue Point meta event
+func NewCuePoint(text string) *Event {
+	return NewMetaEvent(MetaCuePoint, []byte(text))
+}
+
+// NewSMPTEOffset creates a new SMPTE Offset meta event. The frame rate is
+// stored in bits 5 and 6 of the hours byte, as in the file format.
+func NewSMPTEOffset(hours, minutes, seconds, frames, subframes uint8) *Event {
+	return NewMetaEvent(MetaSMPTEOffset, []byte{hours, minutes, seconds, frames, subframes})
+}
+
+// NewSequencerSpecific creates a new Sequencer-Specific meta event
+func NewSequencerSpecific(data []byte) *Event {
+	return NewMetaEvent(MetaSequencerSpecific, data)
+}
+
+// NewEndOfTrack creates a new End of Track meta event
+func NewEndOfTrack() *Event {
+	return NewMetaEvent(MetaEndOfTrack, nil)
+}
+
+// IsMeta checks if an event is a meta event of the given type
+func (e *Event) IsMeta(metaType uint8) bool {
+	return e.Type == EventMeta && !e.systemReset && e.Meta == metaType
+}
+
+// Tempo returns the BPM of a Set Tempo meta event
+func (e *Event) Tempo() (float64, bool) {
+	if !e.IsMeta(MetaSetTempo) || len(e.Data) != 3 {
+		return 0, false
+	}
+	microseconds := uint32(e.Data[0])<<16 | uint32(e.Data[1])<<8 | uint32(e.Data[2])
+	if microseconds == 0 {
+		return 0, false
+	}
+	return 60000000.0 / float64(microseconds), true
+}
+
+// TimeSignature returns the numerator and denominator of a Time Signature meta event
+func (e *Event) TimeSignature() (numerator, denominator uint8, ok bool) {
+	if !e.IsMeta(MetaTimeSignature) || len(e.Data) != 4 || e.Data[1] > 7 {
+		return 0, 0, false
+	}
+	return e.Data[0], 1 << e.Data[1], true
+}
+
+// KeySignature returns the number of sharps (or flats, if negative) and the mode of a Key Signature meta event
+func (e *Event) KeySignature() (sharps int8, minor bool, ok bool) {
+	if !e.IsMeta(MetaKeySignature) || len(e.Data) != 2 {
+		return 0, false, false
+	}
+	return int8(e.Data[0]), e.Data[1] == 1, true
+}
+
+// Text returns the text of a text meta event, like a track name, lyric or marker
+func (e *Event) Text() (string, bool) {
+	if e.Type != EventMeta || e.Meta < MetaText || e.Meta > 0x0F {
+		return "", false
+	}
+	return string(e.Data), true
+}
+
+// hasTempo checks if any of the tracks has a Set Tempo meta event
+func (m *MIDI) hasTempo() bool {
+	for _, t := range m.Tracks {
+		for _, e := range t.Events {
+			if _, ok := e.Tempo(); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// outputTracks returns the tracks that should be written. If none of the
+// tracks has a Set Tempo meta event, one is added from the BPM: in a
+// conductor track for format 1, or at the start of each track otherwise.
+func (m *MIDI) outputTracks() []*Track {
+	if m.BPM <= 0 || m.hasTempo() {
+		return m.Tracks
+	}
+	if m.Format == 1 {
+		conductor := NewTrack()
+		conductor.AddEvent(NewTempo(m.BPM))
+		return append([]*Track{conductor}, m.Tracks...)
+	}
+	tracks := make([]*Track, len(m.Tracks))
+	for i, t := range m.Tracks {
+		tracks[i] = &Track{
+			NoteMap: t.NoteMap,
+			Events:  append([]*Event{NewTempo(m.BPM)}, t.Events...),
+		}
+	}
+	return tracks
+}
@@ -0,0 +1,95 @@
+package midi
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestMetaEvents(t *testing.T) {
+	tempo := NewTempo(120)
+	if !bytes.Equal(tempo.Data, []byte{0x07, 0xA1, 0x20}) {
+		t.Errorf("NewTempo(120) data = %X, want 07 A1 20", tempo.Data)
+	}
+	if bpm, ok := tempo.Tempo(); !ok || math.Abs(bpm-120) > 0.001 {
+		t.Errorf("Tempo() = %f, %v, want 120, true", bpm, ok)
+	}
+
+	timeSignature, err := NewTimeSignature(6, 8)
+	if err != nil {
+		t.Fatalf("NewTimeSignature failed: %v", err)
+	}
+	if numerator, denominator, ok := timeSignature.TimeSignature(); !ok || numerator != 6 || denominator != 8 {
+		t.Errorf("TimeSignature() = %d/%d, %v, want 6/8, true", numerator, denominator, ok)
+	}
+	if _, err := NewTimeSignature(4, 6); err == nil {
+		t.Error("NewTimeSignature(4, 6) should fail")
+	}
+
+	keySignature, err := NewKeySignature(-3, true)
+	if err != nil {
+		t.Fatalf("NewKeySignature failed: %v", err)
+	}
+	if sharps, minor, ok := keySignature.KeySignature(); !ok || sharps != -3 || !minor {
+		t.Errorf("KeySignature() = %d, %v, %v, want -3, true, true", sharps, minor, ok)
+	}
+	if _, err := NewKeySignature(8, false); err == nil {
+		t.Error("NewKeySignature(8, false) should fail")
+	}
+
+	if text, ok := NewLyric("la").Text(); !ok || text != "la" {
+		t.Errorf("Text() = %q, %v, want \"la\", true", text, ok)
+	}
+	if _, ok := tempo.Text(); ok {
+		t.Error("Text() should fail for a Set Tempo event")
+	}
+	if size := NewTrackName("Piano").Size(); size != 8 {
+		t.Errorf("Size() of a track name event = %d, want 8", size)
+	}
+}
+
+func TestWriteEndOfTrackAndTempo(t *testing.T) {
+	m := NewMIDI(1, 480, 90)
+	track := NewTrack()
+	track.AddEvent(NewTrackName("Melody"))
+	track.AddEvent(&Event{Tick: 960, Type: EventNoteOn, Channel: 1, Data: []byte{60, 64}})
+	track.AddEvent(&Event{Tick: 1920, Type: EventNoteOff, Channel: 1, Data: []byte{60, 0}})
+	m.AddTrack(track)
+
+	buf := new(bytes.Buffer)
+	if err := m.Write(buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	m2, err := Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(m2.Tracks) != 2 {
+		t.Fatalf("Read %d tracks, want a conductor track and 1 track", len(m2.Tracks))
+	}
+	if math.Abs(m2.BPM-90) > 0.001 {
+		t.Errorf("BPM = %f, want 90", m2.BPM)
+	}
+	for i, track := range m2.Tracks {
+		last := track.Events[len(track.Events)-1]
+		if !last.IsMeta(MetaEndOfTrack) {
+			t.Errorf("Track %d does not end with an End of Track event", i+1)
+		}
+	}
+	if last := m2.Tracks[1].Events[len(m2.Tracks[1].Events)-1]; last.Tick != 1920 {
+		t.Errorf("End of Track is at tick %d, want 1920", last.Tick)
+	}
+
+	// Writing the file again should not add another conductor track
+	buf.Reset()
+	if err := m2.Write(buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	m3, err := Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(m3.Tracks) != 2 {
+		t.Errorf("Read %d tracks after writing twice, want 2", len(m3.Tracks))
+	}
+}
@@ -0,0 +1,169 @@
+package midi
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Position is a position in a song in bars, beats and ticks, like "bar 5, beat 3".
+// Bars and beats start at 1, and the ticks are from the start of the beat.
+type Position struct {
+	Bar  int
+	Beat int
+	Tick uint32
+}
+
+// String returns the position as bar:beat:tick, like "5:3:0"
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d:%d", p.Bar, p.Beat, p.Tick)
+}
+
+// MeterMap converts between absolute ticks and bars and beats, for a song that may change time signature
+type MeterMap struct {
+	division uint16
+	changes  []meterChange
+}
+
+// meterChange is a time signature that starts at the given tick
+type meterChange struct {
+	tick        uint32
+	numerator   uint8
+	denominator uint8
+	bar         int // the number of bars before the tick, from 0
+}
+
+// NewMeterMap creates a new MeterMap with the given time division and starting time signature
+func NewMeterMap(division uint16, numerator, denominator uint8) *MeterMap {
+	if numerator == 0 || denominator == 0 {
+		numerator, denominator = 4, 4
+	}
+	return &MeterMap{
+		division: division,
+		changes:  []meterChange{{tick: 0, numerator: numerator, denominator: denominator}},
+	}
+}
+
+// MeterMap creates a MeterMap from the Time Signature events in all the tracks,
+// in 4/4 time until the first one
+func (m *MIDI) MeterMap() *MeterMap {
+	mm := NewMeterMap(m.Division, 4, 4)
+	for _, t := range m.Tracks {
+		for _, e := range t.Events {
+			if numerator, denominator, ok := e.TimeSignature(); ok {
+				mm.SetTimeSignature(e.Tick, numerator, denominator)
+			}
+		}
+	}
+	return mm
+}
+
+// SetTimeSignature changes the time signature from the given tick and onwards. A
+// time signature change always starts a new bar, so a bar that is not complete at
+// the tick is shortened.
+func (mm *MeterMap) SetTimeSignature(tick uint32, numerator, denominator uint8) {
+	if numerator == 0 || denominator == 0 {
+		return
+	}
+	change := meterChange{tick: tick, numerator: numerator, denominator: denominator}
+	i := sort.Search(len(mm.changes), func(i int) bool {
+		return mm.changes[i].tick >= tick
+	})
+	if i < len(mm.changes) && mm.changes[i].tick == tick {
+		mm.changes[i] = change
+	} else {
+		mm.changes = append(mm.changes, meterChange{})
+		copy(mm.changes[i+1:], mm.changes[i:])
+		mm.changes[i] = change
+	}
+
+	// Update the bar numbers of the time signature changes
+	for i := 1; i < len(mm.changes); i++ {
+		previous := mm.changes[i-1]
+		barTicks := mm.barTicks(previous)
+		mm.changes[i].bar = previous.bar + int((mm.changes[i].tick-previous.tick+barTicks-1)/barTicks)
+	}
+}
+
+// TimeSignature returns the time signature at the given tick
+func (mm *MeterMap) TimeSignature(tick uint32) (numerator, denominator uint8) {
+	change := mm.changes[mm.changeAtTick(tick)]
+	return change.numerator, change.denominator
+}
+
+// Position converts an absolute tick to bars, beats and ticks. Compound meters, like
+// 6/8 and 12/8, have beats of three notes, so that 6/8 has two beats in a bar.
+func (mm *MeterMap) Position(tick uint32) Position {
+	change := mm.changes[mm.changeAtTick(tick)]
+	barTicks, beatTicks := mm.barTicks(change), mm.beatTicks(change)
+	offset := tick - change.tick
+	inBar := offset % barTicks
+	return Position{
+		Bar:  change.bar + int(offset/barTicks) + 1,
+		Beat: int(inBar/beatTicks) + 1,
+		Tick: inBar % beatTicks,
+	}
+}
+
+// Tick converts bars, beats and ticks to an absolute tick. The beat and the ticks
+// may be larger than a bar or a beat, and then count on into the next bars.
+func (mm *MeterMap) Tick(p Position) (uint32, error) {
+	if p.Bar < 1 || p.Beat < 1 {
+		return 0, fmt.Errorf("invalid position %s, bars and beats start at 1", p)
+	}
+	bar := p.Bar - 1
+	i := sort.Search(len(mm.changes), func(i int) bool {
+		return mm.changes[i].bar > bar
+	}) - 1
+	change := mm.changes[i]
+	tick := uint64(change.tick) + uint64(bar-change.bar)*uint64(mm.barTicks(change)) +
+		uint64(p.Beat-1)*uint64(mm.beatTicks(change)) + uint64(p.Tick)
+	if tick > uint64(^uint32(0)) {
+		return 0, fmt.Errorf("position %s is too far from the start of the song", p)
+	}
+	return uint32(tick), nil
+}
+
+// changeAtTick returns the index of the time signature change that is in effect at the given tick
+func (mm *MeterMap) changeAtTick(tick uint32) int {
+	return sort.Search(len(mm.changes), func(i int) bool {
+		return mm.changes[i].tick > tick
+	}) - 1
+}
+
+// compound checks if a time signature is a compound meter, like 6/8, 9/8 or 12/8
+func (change meterChange) compound() bool {
+	return change.numerator > 3 && change.numerator%3 == 0 && change.denominator >= 8
+}
+
+// beatTicks returns the length of a beat in ticks, which is three notes in compound meters
+func (mm *MeterMap) beatTicks(change meterChange) uint32 {
+	ticks := uint32(mm.division) * 4 / uint32(change.denominator)
+	if change.compound() {
+		ticks *= 3
+	}
+	if ticks == 0 {
+		return 1
+	}
+	return ticks
+}
+
+// barTicks returns the length of a bar in ticks
+func (mm *MeterMap) barTicks(change meterChange) uint32 {
+	ticks := uint32(mm.division) * 4 * uint32(change.numerator) / uint32(change.denominator)
+	if ticks == 0 {
+		return 1
+	}
+	return ticks
+}
+
+// AddNoteAt adds a note to a track like AddNote, starting at a position in bars and
+// beats instead of at the EventDelay of the note, which is set from the position
+func (m *MIDI) AddNoteAt(t *Track, note *Note, p Position) error {
+	tick, err := m.MeterMap().Tick(p)
+	if err != nil {
+		return err
+	}
+	tempoMap := m.TempoMap()
+	note.EventDelay = tempoMap.TicksToDuration(tick)
+	return m.addNote(t, note, tempoMap)
+}
@@ -0,0 +1,77 @@
+package midi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeterMapPosition(t *testing.T) {
+	mm := NewMeterMap(96, 4, 4)
+	// Two and a half bars of 4/4, then 6/8, then 3/4
+	mm.SetTimeSignature(960, 6, 8)
+	mm.SetTimeSignature(960+2*288, 3, 4)
+
+	tests := []struct {
+		tick uint32
+		want Position
+	}{
+		{0, Position{1, 1, 0}},
+		{95, Position{1, 1, 95}},
+		{96 * 5, Position{2, 2, 0}},
+		{96*9 + 10, Position{3, 2, 10}},
+		{960, Position{4, 1, 0}},
+		{960 + 144, Position{4, 2, 0}},
+		{960 + 288 + 150, Position{5, 2, 6}},
+		{960 + 2*288, Position{6, 1, 0}},
+		{960 + 2*288 + 3*96 + 96, Position{7, 2, 0}},
+	}
+	for _, test := range tests {
+		got := mm.Position(test.tick)
+		if got != test.want {
+			t.Errorf("Position(%d) = %s, want %s", test.tick, got, test.want)
+		}
+		tick, err := mm.Tick(test.want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tick != test.tick {
+			t.Errorf("Tick(%s) = %d, want %d", test.want, tick, test.tick)
+		}
+	}
+
+	if numerator, denominator := mm.TimeSignature(1000); numerator != 6 || denominator != 8 {
+		t.Errorf("got time signature %d/%d at tick 1000, want 6/8", numerator, denominator)
+	}
+	if _, err := mm.Tick(Position{0, 1, 0}); err == nil {
+		t.Errorf("bar 0 should be invalid")
+	}
+	// Beats past the end of the bar count on into the next bar
+	if tick, _ := mm.Tick(Position{1, 5, 0}); tick != 384 {
+		t.Errorf("got tick %d for beat 5 of bar 1, want 384", tick)
+	}
+}
+
+func TestMIDIMeterMap(t *testing.T) {
+	m := NewMIDI(1, 96, 120)
+	track := NewTrack()
+	ts, err := NewTimeSignature(3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	track.AddEventAt(0, ts)
+	m.AddTrack(track)
+
+	if err := m.AddNoteAt(track, &Note{Frequency: 440, Duration: time.Second / 4, Velocity: 100, Channel: 1}, Position{Bar: 5, Beat: 3}); err != nil {
+		t.Fatal(err)
+	}
+	notes := track.Notes()
+	if len(notes) != 1 {
+		t.Fatalf("got %d notes, want 1", len(notes))
+	}
+	if want := uint32(4*3*96 + 2*96); notes[0].Tick != want {
+		t.Errorf("the note starts at tick %d, want %d", notes[0].Tick, want)
+	}
+	if p := m.MeterMap().Position(notes[0].Tick); p != (Position{5, 3, 0}) {
+		t.Errorf("the note is at %s, want 5:3:0", p)
+	}
+}
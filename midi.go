@@ -3,6 +3,7 @@ package midi
 import (
 	"fmt"
 	"io"
+	"math"
 	"sort"
 	"strings"
 	"time"
@@ -13,7 +14,11 @@ const (
 	EventNoteOff       = 0x80
 	EventNoteOn        = 0x90
 	EventProgramChange = 0xC0
+	EventSysEx         = 0xF0
+	EventSysExEscape   = 0xF7
+	EventMeta          = 0xFF
 
+	DefaultBPM            = 120
 	DefaultNoteDuration   = 500 * time.Millisecond
 	DefaultNoteVelocity   = 64
 	DefaultNoteChannel    = 1
@@ -28,6 +33,27 @@ type MIDI struct {
 	BPM            float64
 	Tracks         []*Track
 	ChannelProgram map[uint8]uint8
+
+	// PitchBendRange is the pitch bend range in semitones. If it is above 0,
+	// AddNote emits pitch bend events for frequencies between MIDI notes.
+	PitchBendRange float64
+
+	// MPEChannels are the channels that AddNote may move notes to, when notes
+	// that play at the same time on the same channel need different pitch bends.
+	MPEChannels []uint8
+
+	// Tuning is the tuning of notes that are given as a Pitch. If it is set
+	// together with PitchBendRange, AddNote emits pitch bends so that the notes
+	// sound as in this tuning on a synthesizer with standard tuning. Without
+	// PitchBendRange, AddNote adds a MIDI Tuning Standard bulk tuning dump instead,
+	// and selects it on each channel that plays a note with a Pitch.
+	Tuning Tuning
+
+	bendSpans      map[uint8][]bendSpan // the pitch bends that are in use, per channel
+	bendRangeSent  map[uint8]bool       // the channels that have received the pitch bend range
+	tuningSent     bool                 // if a tuning dump has been added
+	tuningSelected map[uint8]bool       // the channels where the tuning of the dump has been selected
+	variations     map[uint8]uint8      // the GM2 variations that have been selected with bank select, per channel
 }
 
 // Track represents a track in a MIDI file or a sequence of MIDI events
@@ -38,21 +64,32 @@ type Track struct {
 
 // Event represents a MIDI event
 type Event struct {
-	DeltaTime uint32
-	Type      uint8
-	Channel   uint8
+	Tick      uint32 // The absolute position of the event in the track, in ticks
+	DeltaTime uint32 // The ticks since the previous event, set when the track is read or written
+	Type      uint8  // The event type, without the channel for channel messages
+	Channel   uint8  // The MIDI channel, from 1 to 16
 	Program   uint8
+	Meta      uint8 // The meta event type, only used when Type is EventMeta
 	Data      []byte
+
+	systemReset bool // if the event is a System Reset from NewSystemReset, and not a meta event
 }
 
 // Note represents a musical note in a MIDI track
 type Note struct {
+	Pitch      *Pitch // The pitch of the note, used instead of Frequency if set
 	Frequency  float64
 	Duration   time.Duration
 	Velocity   uint8
-	Channel    uint8
+	Channel    uint8         // The MIDI channel, from 1 to 16
 	Program    uint8         // The sound to use for the note
-	EventDelay time.Duration // Wait before the note should be played (from previous note? from the start of the track?)
+	Variation  uint8         // The GM2 variation of the program, selected with bank select if it changes
+	Instrument string        // The GM1 or GM2 instrument name, used instead of Program and Variation if set
+	Drum       string        // The General MIDI percussion name, played on DrumChannel instead of the frequency if set
+	EventDelay time.Duration // When the note should be played, from the start of the track
+
+	Tick          uint32 // The start of the note in ticks, set by Track.Notes
+	DurationTicks uint32 // The length of the note in ticks, set by Track.Notes
 }
 
 // NewMIDI creates a new MIDI file or sequence of MIDI events
@@ -84,86 +121,220 @@ func (t *Track) AddEvent(event *Event) {
 	t.Events = append(t.Events, event)
 }
 
-func (m *MIDI) AddNote(t *Track, note *Note) {
-	// Convert frequency to MIDI note
-	midiNote, _ := FrequencyToMidi(note.Frequency)
+// AddNote adds a note to a track, as "note on" and "note off" events.
+// If PitchBendRange is set, a pitch bend event is added before the "note on" event.
+// Bank select controllers are added before the program change when the GM2 variation changes.
+func (m *MIDI) AddNote(t *Track, note *Note) error {
+	return m.addNote(t, note, m.TempoMap())
+}
 
-	// Convert the note start pause and duration to ticks
-	eventDelayTicks := m.DurationToTicks(note.EventDelay)
-	durationTicks := m.DurationToTicks(note.Duration)
+// addNote adds a note to a track like AddNote, with a tempo map that is built
+// once for all the notes that are added at the same time
+func (m *MIDI) addNote(t *Track, note *Note, tempoMap *TempoMap) error {
+	channel := note.Channel
+	program, variation := note.Program, note.Variation
+	if note.Instrument != "" {
+		var err error
+		if program, variation, err = ProgramVariationByName(note.Instrument); err != nil {
+			return err
+		}
+	}
+
+	// Use the key of the drum, or convert the pitch or the frequency to a MIDI note
+	var (
+		midiNote uint8
+		bend     int
+	)
+	switch {
+	case note.Drum != "":
+		key, err := DrumKey(note.Drum)
+		if err != nil {
+			return err
+		}
+		midiNote, channel = key, DrumChannel
+	case note.Pitch != nil && m.Tuning != nil && m.PitchBendRange > 0:
+		// The pitch bend is from the standard tuning of the synthesizer
+		frequency := note.Pitch.Frequency(m.Tuning)
+		if frequency <= 0 {
+			return fmt.Errorf("pitch %s has no frequency in the tuning", note.Pitch)
+		}
+		var err error
+		if midiNote, bend, err = FrequencyToMidiIn(frequency, nil); err != nil {
+			return fmt.Errorf("pitch %s: %w", note.Pitch, err)
+		}
+	case note.Pitch != nil:
+		number := note.Pitch.Number + int(math.Floor(note.Pitch.Cents/100+0.5))
+		if number < 0 || number > 127 {
+			return fmt.Errorf("pitch %s is outside of the MIDI note range", note.Pitch)
+		}
+		midiNote = uint8(number)
+		bend = int(math.Round((note.Pitch.Cents/100 - float64(number-note.Pitch.Number)) * 8192))
+	default:
+		var err error
+		if midiNote, bend, err = FrequencyToMidiIn(note.Frequency, nil); err != nil {
+			return err
+		}
+	}
+
+	if channel < 1 || channel > 16 {
+		return fmt.Errorf("invalid channel %d, must be from 1 to 16", channel)
+	}
+	if note.Pitch != nil && m.Tuning != nil && m.PitchBendRange <= 0 && note.Drum == "" {
+		// The synthesizer is retuned, so the MIDI note number can be used as it is.
+		// The note is valid, so the tuning can be added to the track.
+		if !m.tuningSent {
+			if err := m.AddTuning(t, m.Tuning); err != nil {
+				return err
+			}
+		}
+		m.addTuningSelect(t, channel)
+	}
+
+	// Convert the note start and end times to absolute ticks
+	startTick := tempoMap.DurationToTicks(note.EventDelay)
+	endTick := tempoMap.DurationToTicks(note.EventDelay + note.Duration)
+	if endTick <= startTick {
+		// A note must last at least one tick, since "note off" events are sorted
+		// before "note on" events at the same tick
+		endTick = startTick + 1
+	}
+
+	var pitchBend *Event
+	if m.PitchBendRange > 0 && note.Drum == "" {
+		// Find a channel where the pitch bend does not disturb other notes
+		value := pitchBendValue(float64(bend)/8192, m.PitchBendRange)
+		var err error
+		if channel, err = m.allocateBendChannel(channel, startTick, endTick, value); err != nil {
+			return err
+		}
+		m.addBendRange(t, channel)
+		pitchBend = &Event{
+			Tick:    startTick,
+			Type:    PitchBend,
+			Channel: channel,
+			Program: program,
+			Data:    []byte{byte(value & 0x7F), byte(value >> 7)},
+		}
+	}
+
+	// Select the GM2 bank of the variation, which takes effect at the next program change
+	bankSelect := variation != m.variations[channel]
+	if bankSelect {
+		t.AddEvent(&Event{Tick: startTick, Type: ControlChange, Channel: channel, Program: program, Data: []byte{controllerBankSelectMSB, GM2MelodyBank}})
+		t.AddEvent(&Event{Tick: startTick, Type: ControlChange, Channel: channel, Program: program, Data: []byte{controllerBankSelectLSB, variation}})
+		if m.variations == nil {
+			m.variations = make(map[uint8]uint8)
+		}
+		m.variations[channel] = variation
+	}
 
 	// Check if program change is needed
-	currentProgram := m.GetProgram(note.Channel)
-	if note.Program != currentProgram {
+	currentProgram := m.GetProgram(channel)
+	if program != currentProgram || bankSelect {
 		// Create program change event
 		programChange := &Event{
-			DeltaTime: eventDelayTicks,
-			Type:      EventProgramChange,
-			Channel:   note.Channel,
-			Program:   note.Program,
-			Data:      []byte{note.Program},
+			Tick:    startTick,
+			Type:    EventProgramChange,
+			Channel: channel,
+			Program: program,
+			Data:    []byte{program},
 		}
 		t.AddEvent(programChange)
-		m.SetProgram(note.Channel, note.Program)
+		m.SetProgram(channel, program)
+	}
+
+	if pitchBend != nil {
+		t.AddEvent(pitchBend)
 	}
 
 	// Create "note on" event
 	noteOn := &Event{
-		DeltaTime: eventDelayTicks,
-		Type:      EventNoteOn,
-		Channel:   note.Channel,
-		Program:   note.Program,
-		Data:      []byte{midiNote, note.Velocity},
+		Tick:    startTick,
+		Type:    EventNoteOn,
+		Channel: channel,
+		Program: program,
+		Data:    []byte{midiNote, note.Velocity},
 	}
 
 	// Create "note off" event
 	noteOff := &Event{
-		DeltaTime: eventDelayTicks + durationTicks, // delay after the "note on" event
-		Type:      EventNoteOff,
-		Channel:   note.Channel,
-		Program:   note.Program,
-		Data:      []byte{midiNote, 0}, // Velocity is 0
+		Tick:    endTick,
+		Type:    EventNoteOff,
+		Channel: channel,
+		Program: program,
+		Data:    []byte{midiNote, 0}, // Velocity is 0
 	}
 
 	// Add the events to the track
 	t.AddEvent(noteOn)
 	t.AddEvent(noteOff)
+	return nil
+}
+
+// IsNoteOff checks if an event is a "note off" event, or a "note on" event with velocity 0
+func (e *Event) IsNoteOff() bool {
+	return e.Type == EventNoteOff || (e.Type == EventNoteOn && len(e.Data) > 1 && e.Data[1] == 0)
+}
+
+// SortEvents sorts the events in a track by their tick, and updates the delta times.
+// Events at the same tick keep their order, except that "note off" events come first.
+func (t *Track) SortEvents() {
+	t.Events = sortedEvents(t.Events)
+	updateDeltaTimes(t.Events)
+}
+
+// sortedEvents returns a sorted copy of a list of events
+func sortedEvents(events []*Event) []*Event {
+	sorted := make([]*Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Tick != sorted[j].Tick {
+			return sorted[i].Tick < sorted[j].Tick
+		}
+		return sorted[i].IsNoteOff() && !sorted[j].IsNoteOff()
+	})
+	return sorted
+}
+
+// updateDeltaTimes sets the delta time of each event from the ticks of a sorted list of events
+func updateDeltaTimes(events []*Event) {
+	var previous uint32
+	for _, e := range events {
+		e.DeltaTime = e.Tick - previous
+		previous = e.Tick
+	}
 }
 
 // Size returns the byte size of an Event
 func (e *Event) Size() int {
+	switch e.Type {
+	case EventMeta:
+		// 1 byte for the event type, 1 for the meta type, then the length and the data
+		return 2 + variableLengthQuantitySize(uint32(len(e.Data))) + len(e.Data)
+	case EventSysEx, EventSysExEscape:
+		// 1 byte for the event type, then the length and the data
+		return 1 + variableLengthQuantitySize(uint32(len(e.Data))) + len(e.Data)
+	}
 	return 1 + len(e.Data) // 1 byte for the event type, plus the size of the data
 }
 
 // Write writes the MIDI data to an io.Writer.
+// An End of Track event is added to each track, and a Set Tempo event is
+// added from the BPM if there is none. Format 0 must have exactly one track.
 func (m *MIDI) Write(w io.Writer) error {
-	// Write MIDI header
-	if err := writeMIDIHeader(w, m); err != nil {
-		return err
-	}
-
-	// Write each track
-	for _, track := range m.Tracks {
-		if err := writeTrack(w, track); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return WriteMIDI(w, m)
 }
 
-// DurationToTicks converts a time duration to the number of ticks
+// DurationToTicks converts a time from the start of the song to an absolute tick,
+// following any tempo changes. Use TempoMap for converting many times.
 func (m *MIDI) DurationToTicks(d time.Duration) uint32 {
-	ticksPerBeat := float64(m.Division)
-	ticksPerSecond := ticksPerBeat * m.BPM / 60.0
-	return uint32(d.Seconds() * ticksPerSecond)
+	return m.TempoMap().DurationToTicks(d)
 }
 
-// TicksToDuration converts the number of ticks to a time duration
+// TicksToDuration converts an absolute tick to the time from the start of the song,
+// following any tempo changes. Use TempoMap for converting many ticks.
 func (m *MIDI) TicksToDuration(ticks uint32) time.Duration {
-	ticksPerBeat := float64(m.Division)
-	ticksPerSecond := ticksPerBeat * m.BPM / 60.0
-	return time.Duration(float64(ticks) / ticksPerSecond * float64(time.Second))
+	return m.TempoMap().TicksToDuration(ticks)
 }
 
 // NewNoteMap creates a new map for storing notes by their start time
@@ -177,16 +348,17 @@ func (t *Track) AddNoteToMap(startTime time.Duration, note *Note) {
 }
 
 // AddNotesFromMap adds notes to a track from its note map
-func (t *Track) AddNotesFromMap(m *MIDI) {
-	m.AddNotesFromMap(t, t.NoteMap)
+func (t *Track) AddNotesFromMap(m *MIDI) error {
+	return m.AddNotesFromMap(t, t.NoteMap)
 }
 
-func (m *MIDI) Commit(t *Track) {
-	m.AddNotesFromMap(t, t.NoteMap)
+// Commit adds the notes in the note map of a track to the track
+func (m *MIDI) Commit(t *Track) error {
+	return m.AddNotesFromMap(t, t.NoteMap)
 }
 
 // AddNotesFromMap adds notes to a track from a map by their start time
-func (m *MIDI) AddNotesFromMap(t *Track, noteMap map[time.Duration][]*Note) {
+func (m *MIDI) AddNotesFromMap(t *Track, noteMap map[time.Duration][]*Note) error {
 	// Convert map to a list of note start times and sort it
 	var startTimes []time.Duration
 	for startTime := range noteMap {
@@ -197,20 +369,21 @@ func (m *MIDI) AddNotesFromMap(t *Track, noteMap map[time.Duration][]*Note) {
 	})
 
 	// Add notes to track in order of start time
+	tempoMap := m.TempoMap()
 	for _, startTime := range startTimes {
 		notes := noteMap[startTime]
-		for i, note := range notes {
-			// Adjust the note's start delay to be relative to the last note only for the first note in a chord
-			if i == 0 {
-				note.EventDelay = startTime
-			} else {
-				note.EventDelay = 0
+		for _, note := range notes {
+			// All the notes in a chord start at the same time
+			note.EventDelay = startTime
+			if err := m.addNote(t, note, tempoMap); err != nil {
+				return err
 			}
-			m.AddNote(t, note)
 		}
 	}
+	return nil
 }
 
+// AddNoteFromNoteString adds a note like "C#4:500ms" to the note map of a track
 func (m *MIDI) AddNoteFromNoteString(t *Track, noteString string, eventDelay, noteDuration time.Duration) error {
 	note := &Note{
 		EventDelay: eventDelay,
@@ -225,8 +398,12 @@ func (m *MIDI) AddNoteFromNoteString(t *Track, noteString string, eventDelay, no
 		return fmt.Errorf("invalid note string format")
 	}
 
-	noteName := parts[0]
-	note.Frequency = NoteNameToFrequency(noteName)
+	pitch, err := ParseNoteName(parts[0])
+	if err != nil {
+		return err
+	}
+	note.Pitch = &pitch
+	note.Frequency = midiNumberToFrequency(float64(pitch.Number))
 
 	duration, err := time.ParseDuration(parts[1])
 	if err != nil {
@@ -238,22 +415,35 @@ func (m *MIDI) AddNoteFromNoteString(t *Track, noteString string, eventDelay, no
 	return nil
 }
 
-func CreateChord(notes []string, eventDelay time.Duration) []Note {
+// CreateChord creates notes that start at the same time from note names like "C4".
+// An error is returned if a note name is invalid.
+func CreateChord(notes []string, eventDelay time.Duration) ([]Note, error) {
 	var chord []Note
 	for _, note := range notes {
+		pitch, err := ParseNoteName(note)
+		if err != nil {
+			return nil, err
+		}
 		chord = append(chord, Note{
-			Frequency:  NoteNameToFrequency(note),
+			Pitch:      &pitch,
+			Frequency:  midiNumberToFrequency(float64(pitch.Number)),
 			Duration:   time.Second, // each note lasts for 1 second
 			Velocity:   127,
 			Channel:    1,
 			EventDelay: eventDelay,
 		})
 	}
-	return chord
+	return chord, nil
 }
 
-func (m *MIDI) AddChord(notes []string, eventDelay time.Duration) {
-	chord := CreateChord(notes, eventDelay)
+// AddChord adds notes that start at the same time from note names like "C4".
+// An error is returned if a note name is invalid.
+func (m *MIDI) AddChord(notes []string, eventDelay time.Duration) error {
+	chord, err := CreateChord(notes, eventDelay)
+	if err != nil {
+		return err
+	}
+	tempoMap := m.TempoMap()
 	for _, note := range chord {
 		// If enough tracks exist, use them. Otherwise, create a new track.
 		var t *Track
@@ -263,6 +453,9 @@ func (m *MIDI) AddChord(notes []string, eventDelay time.Duration) {
 			t = NewTrack()
 			m.AddTrack(t)
 		}
-		m.AddNote(t, &note)
+		if err := m.addNote(t, &note, tempoMap); err != nil {
+			return err
+		}
 	}
+	return nil
 }
@@ -0,0 +1,173 @@
+package midi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestAddNotesFromMapTicks(t *testing.T) {
+	m := NewMIDI(1, 480, 120) // 960 ticks per second
+	track := NewTrack()
+	m.AddTrack(track)
+
+	// A chord at the start, then a long note that overlaps with a short note
+	for _, noteString := range []string{"C4:1s", "E4:1s", "G4:1s"} {
+		if err := m.AddNoteFromNoteString(track, noteString, 0, time.Second); err != nil {
+			t.Fatalf("AddNoteFromNoteString failed: %v", err)
+		}
+	}
+	if err := m.AddNoteFromNoteString(track, "A4:2s", time.Second, 0); err != nil {
+		t.Fatalf("AddNoteFromNoteString failed: %v", err)
+	}
+	if err := m.AddNoteFromNoteString(track, "C5:500ms", 1500*time.Millisecond, 0); err != nil {
+		t.Fatalf("AddNoteFromNoteString failed: %v", err)
+	}
+	m.Commit(track)
+
+	buf := new(bytes.Buffer)
+	if err := m.Write(buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	m2, err := Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	// Find the start and end ticks of each note
+	starts := make(map[uint8]uint32)
+	ends := make(map[uint8]uint32)
+	var previous *Event
+	for _, e := range m2.Tracks[1].Events {
+		if previous != nil && previous.Tick == e.Tick && e.IsNoteOff() && !previous.IsNoteOff() && previous.Type == EventNoteOn {
+			t.Errorf("Note off at tick %d comes after a note on at the same tick", e.Tick)
+		}
+		switch {
+		case e.IsNoteOff():
+			ends[e.Data[0]] = e.Tick
+		case e.Type == EventNoteOn:
+			starts[e.Data[0]] = e.Tick
+		}
+		if e.Type != EventMeta {
+			previous = e
+		}
+	}
+
+	want := map[uint8][2]uint32{
+		60: {0, 960},     // C4
+		64: {0, 960},     // E4
+		67: {0, 960},     // G4
+		69: {960, 2880},  // A4
+		72: {1440, 1920}, // C5
+	}
+	for note, ticks := range want {
+		if starts[note] != ticks[0] || ends[note] != ticks[1] {
+			t.Errorf("Note %d plays from tick %d to %d, want %d to %d", note, starts[note], ends[note], ticks[0], ticks[1])
+		}
+	}
+}
+
+func TestSortEvents(t *testing.T) {
+	track := NewTrack()
+	track.AddEvent(&Event{Tick: 480, Type: EventNoteOn, Channel: 1, Data: []byte{60, 64}})
+	track.AddEvent(&Event{Tick: 0, Type: EventNoteOn, Channel: 1, Data: []byte{62, 64}})
+	track.AddEvent(&Event{Tick: 480, Type: EventNoteOff, Channel: 1, Data: []byte{62, 0}})
+	track.SortEvents()
+
+	wantTicks := []uint32{0, 480, 480}
+	wantDeltas := []uint32{0, 480, 0}
+	for i, e := range track.Events {
+		if e.Tick != wantTicks[i] || e.DeltaTime != wantDeltas[i] {
+			t.Errorf("Event %d has tick %d and delta time %d, want %d and %d", i, e.Tick, e.DeltaTime, wantTicks[i], wantDeltas[i])
+		}
+	}
+	if !track.Events[1].IsNoteOff() {
+		t.Errorf("The note off event should come before the note on event at tick 480")
+	}
+}
+
+func TestAddShortNote(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	track := NewTrack()
+	m.AddTrack(track)
+	if err := m.AddNote(track, &Note{Frequency: 110, Duration: 500 * time.Microsecond, Velocity: 100, Channel: 1}); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := m.Write(buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	m2, err := Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	var noteEvents []*Event
+	for _, e := range m2.Tracks[1].Events {
+		if e.Type == EventNoteOn || e.Type == EventNoteOff {
+			noteEvents = append(noteEvents, e)
+		}
+	}
+	if len(noteEvents) != 2 || noteEvents[0].IsNoteOff() || !noteEvents[1].IsNoteOff() {
+		t.Fatalf("A note that is shorter than a tick should be written as note on and then note off, got %+v", noteEvents)
+	}
+	if noteEvents[1].Tick != 1 {
+		t.Errorf("The note ends at tick %d, want 1", noteEvents[1].Tick)
+	}
+}
+
+func TestInvalidNoteNames(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	track := NewTrack()
+	m.AddTrack(track)
+	if err := m.AddNoteFromNoteString(track, "C10:1s", 0, time.Second); err == nil {
+		t.Errorf("AddNoteFromNoteString should fail for a note above the MIDI note range")
+	}
+	if err := m.AddChord([]string{"C4", "E4", "X4"}, 0); err == nil {
+		t.Errorf("AddChord should fail for an invalid note name")
+	}
+	if len(track.NoteMap) != 0 || len(track.Events) != 0 {
+		t.Errorf("No notes should be added for invalid note names")
+	}
+}
+
+func TestAddNoteFromNoteStringPitch(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	m.Tuning = EqualTemperament{A4: 432}
+	m.PitchBendRange = 2
+	track := NewTrack()
+	m.AddTrack(track)
+	if err := m.AddNoteFromNoteString(track, "Bbb4:1s", 0, time.Second); err != nil {
+		t.Fatalf("AddNoteFromNoteString failed: %v", err)
+	}
+	if p := track.NoteMap[0][0].Pitch; p == nil || p.String() != "Bbb4" {
+		t.Errorf("The note should keep the spelling Bbb4, got %v", p)
+	}
+	if err := m.Commit(track); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	for _, e := range track.Events {
+		if e.Type == PitchBend {
+			if value, _ := e.PitchBendValue(); value >= 0 {
+				t.Errorf("A4 at 432Hz should be bent down, got a pitch bend of %d", value)
+			}
+			return
+		}
+	}
+	t.Errorf("Expected a pitch bend event for the tuning")
+}
+
+func TestAddNoteInvalidFrequency(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	m.PitchBendRange = 2
+	track := NewTrack()
+	m.AddTrack(track)
+	for _, frequency := range []float64{0, -440} {
+		if err := m.AddNote(track, &Note{Frequency: frequency, Duration: time.Second, Channel: 1}); err == nil {
+			t.Errorf("AddNote should fail for the frequency %g", frequency)
+		}
+	}
+	if len(track.Events) != 0 {
+		t.Errorf("No events should be added for invalid frequencies, got %d", len(track.Events))
+	}
+}
@@ -2,6 +2,7 @@ package midi
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 )
 
@@ -19,6 +20,21 @@ const (
 	SystemExclusive       = 0xF0
 )
 
+// System common and system real-time status bytes, as used on the wire
+const (
+	MIDITimeCode     = 0xF1
+	SongPosition     = 0xF2
+	SongSelect       = 0xF3
+	TuneRequest      = 0xF6
+	EndOfExclusive   = 0xF7
+	TimingClock      = 0xF8
+	SequenceStart    = 0xFA
+	SequenceContinue = 0xFB
+	SequenceStop     = 0xFC
+	ActiveSensing    = 0xFE
+	SystemReset      = 0xFF
+)
+
 // writeVariableLengthQuantity writes a variable-length quantity (VLQ) to an io.Writer
 func writeVariableLengthQuantity(w io.Writer, value uint32) error {
 	bytes := make([]byte, 0, 4)
@@ -36,12 +52,25 @@ func writeVariableLengthQuantity(w io.Writer, value uint32) error {
 	return err
 }
 
-// readVariableLengthQuantity reads a variable-length quantity (VLQ) from an io.Reader
+// variableLengthQuantitySize returns the number of bytes needed to encode a value as a VLQ
+func variableLengthQuantitySize(value uint32) int {
+	size := 1
+	for value >>= 7; value > 0; value >>= 7 {
+		size++
+	}
+	return size
+}
+
+// readVariableLengthQuantity reads a variable-length quantity (VLQ) from an io.Reader.
+// A VLQ is at most 4 bytes long, for values up to 0x0FFFFFFF.
 func readVariableLengthQuantity(r io.Reader) (uint32, error) {
 	var value uint32
 	var buf [1]byte
 
-	for {
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, fmt.Errorf("variable-length quantity is longer than 4 bytes")
+		}
 		if _, err := r.Read(buf[:]); err != nil {
 			return 0, err
 		}
@@ -0,0 +1,197 @@
+package midi
+
+import (
+	"fmt"
+	"math"
+)
+
+// AllDevices is the device ID that addresses all devices in universal system exclusive messages
+const AllDevices = 0x7F
+
+// Universal system exclusive IDs and MIDI Tuning Standard sub-IDs
+const (
+	universalNonRealTime        = 0x7E
+	universalRealTime           = 0x7F
+	mtsSubID                    = 0x08
+	mtsBulkDump                 = 0x01
+	mtsSingleNoteChange         = 0x02
+	mtsSingleNoteChangeWithBank = 0x07
+	mtsScaleOctave1Byte         = 0x08
+	mtsScaleOctave2Byte         = 0x09
+)
+
+// Registered parameter numbers for selecting a MIDI Tuning Standard tuning
+const (
+	rpnTuningProgram = 0x03
+	rpnTuningBank    = 0x04
+)
+
+// NoteTuning is the frequency of a single MIDI key, for MIDI Tuning Standard messages
+type NoteTuning struct {
+	Key       uint8
+	Frequency float64
+}
+
+// mtsFrequency encodes a frequency as a MIDI Tuning Standard frequency: the
+// equal temperament semitone below it, and the fraction of a semitone above it in
+// units of 100/16384 cents. A frequency of 0 or less gives 7F 7F 7F, which means no change.
+func mtsFrequency(frequency float64) [3]byte {
+	if frequency <= 0 {
+		return [3]byte{0x7F, 0x7F, 0x7F}
+	}
+	exact := math.Log2(frequency/440)*12 + 69
+	if exact < 0 {
+		return [3]byte{0, 0, 0}
+	}
+	semitone := math.Floor(exact)
+	fraction := math.Round((exact - semitone) * 16384)
+	if fraction >= 16384 {
+		semitone++
+		fraction = 0
+	}
+	if semitone >= 127 && fraction >= 16383 || semitone > 127 {
+		// 7F 7F 7F is reserved, so 7F 7F 7E is the highest frequency
+		return [3]byte{0x7F, 0x7F, 0x7E}
+	}
+	f := uint16(fraction)
+	return [3]byte{byte(semitone), byte(f >> 7), byte(f & 0x7F)}
+}
+
+// NewBulkTuningDump creates a MIDI Tuning Standard bulk tuning dump, with the
+// frequencies of all 128 keys in a tuning. The name is up to 16 ASCII characters.
+func NewBulkTuningDump(deviceID, program uint8, name string, tuning Tuning) (*Event, error) {
+	if deviceID > 127 || program > 127 {
+		return nil, fmt.Errorf("invalid device ID %d or tuning program %d, must be from 0 to 127", deviceID, program)
+	}
+	data := []byte{universalNonRealTime, deviceID, mtsSubID, mtsBulkDump, program}
+	for i := 0; i < 16; i++ {
+		c := byte(' ')
+		if i < len(name) && name[i] >= ' ' && name[i] < 0x7F {
+			c = name[i]
+		}
+		data = append(data, c)
+	}
+	for key := 0; key < 128; key++ {
+		frequency := mtsFrequency(tuning.Frequency(key))
+		data = append(data, frequency[:]...)
+	}
+
+	// The checksum is all the bytes after F0 XOR-ed together
+	var checksum byte
+	for _, b := range data {
+		checksum ^= b
+	}
+	data = append(data, checksum&0x7F)
+
+	return NewSysEx(data)
+}
+
+// NewSingleNoteTuningChange creates a MIDI Tuning Standard single note tuning
+// change, which retunes up to 127 keys. Real-time changes apply to notes that are
+// already playing. The bank is only used by real-time changes if it is not 0.
+func NewSingleNoteTuningChange(deviceID, bank, program uint8, changes []NoteTuning, realTime bool) (*Event, error) {
+	if deviceID > 127 || bank > 127 || program > 127 {
+		return nil, fmt.Errorf("invalid device ID %d, tuning bank %d or tuning program %d, must be from 0 to 127", deviceID, bank, program)
+	}
+	if len(changes) > 127 {
+		return nil, fmt.Errorf("too many note tuning changes: %d, must be at most 127", len(changes))
+	}
+
+	var data []byte
+	switch {
+	case realTime && bank == 0:
+		data = []byte{universalRealTime, deviceID, mtsSubID, mtsSingleNoteChange, program}
+	case realTime:
+		data = []byte{universalRealTime, deviceID, mtsSubID, mtsSingleNoteChangeWithBank, bank, program}
+	default:
+		data = []byte{universalNonRealTime, deviceID, mtsSubID, mtsSingleNoteChangeWithBank, bank, program}
+	}
+	data = append(data, byte(len(changes)))
+	for _, change := range changes {
+		if change.Key > 127 {
+			return nil, fmt.Errorf("invalid key %d, must be from 0 to 127", change.Key)
+		}
+		frequency := mtsFrequency(change.Frequency)
+		data = append(data, change.Key)
+		data = append(data, frequency[:]...)
+	}
+
+	return NewSysEx(data)
+}
+
+// NewScaleOctaveTuning creates a MIDI Tuning Standard scale/octave tuning message,
+// which offsets each of the 12 pitch classes from C by the given cents, in every
+// octave, on the given channels (from 1 to 16). The 1 byte format has a range of
+// -64 to 63 cents, and the 2 byte format has a range of -100 to 100 cents.
+func NewScaleOctaveTuning(deviceID uint8, channels []uint8, cents [12]float64, realTime, twoByte bool) (*Event, error) {
+	if deviceID > 127 {
+		return nil, fmt.Errorf("invalid device ID %d, must be from 0 to 127", deviceID)
+	}
+
+	// The channels are given as a bit mask in 3 bytes, with channels 15 and 16 in the first byte
+	var mask uint32
+	for _, channel := range channels {
+		if channel < 1 || channel > 16 {
+			return nil, fmt.Errorf("invalid channel %d, must be from 1 to 16", channel)
+		}
+		mask |= 1 << (channel - 1)
+	}
+
+	universal := byte(universalNonRealTime)
+	if realTime {
+		universal = universalRealTime
+	}
+	format := byte(mtsScaleOctave1Byte)
+	if twoByte {
+		format = mtsScaleOctave2Byte
+	}
+	data := []byte{universal, deviceID, mtsSubID, format, byte(mask >> 14 & 0x03), byte(mask >> 7 & 0x7F), byte(mask & 0x7F)}
+
+	for _, c := range cents {
+		if twoByte {
+			// 0 is -100 cents, 8192 is 0 cents and 16383 is +100 cents
+			value := math.Round(8192 + c/100*8192)
+			value = math.Max(0, math.Min(16383, value))
+			data = append(data, byte(uint16(value)>>7), byte(uint16(value)&0x7F))
+		} else {
+			// 0 is -64 cents, 64 is 0 cents and 127 is +63 cents
+			value := math.Max(0, math.Min(127, math.Round(c)+64))
+			data = append(data, byte(value))
+		}
+	}
+
+	return NewSysEx(data)
+}
+
+// AddTuning adds a MIDI Tuning Standard bulk tuning dump of a tuning to the start
+// of a track, as tuning program 0 for all devices. A synthesizer only stores the
+// tuning until it is selected on a channel, which AddNote does for each channel
+// that plays a note with a Pitch.
+func (m *MIDI) AddTuning(t *Track, tuning Tuning) error {
+	name := "Tuning"
+	if scale, ok := tuning.(*ScaleTuning); ok && scale.Description != "" {
+		name = scale.Description
+	}
+	dump, err := NewBulkTuningDump(AllDevices, 0, name, tuning)
+	if err != nil {
+		return err
+	}
+	t.AddEventAt(0, dump)
+	m.tuningSent = true
+	return nil
+}
+
+// addTuningSelect adds events that select tuning program 0 in tuning bank 0 on a
+// channel with RPN 4 and RPN 3, the first time a tuned note is played on that channel
+func (m *MIDI) addTuningSelect(t *Track, channel uint8) {
+	if m.tuningSelected == nil {
+		m.tuningSelected = make(map[uint8]bool)
+	}
+	if m.tuningSelected[channel] {
+		return
+	}
+	m.tuningSelected[channel] = true
+
+	addRPN(t, channel, 0, rpnTuningBank, 0)
+	addRPN(t, channel, 0, rpnTuningProgram, 0)
+}
@@ -0,0 +1,159 @@
+package midi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMTSFrequency(t *testing.T) {
+	tests := []struct {
+		frequency float64
+		want      [3]byte
+	}{
+		{440, [3]byte{69, 0, 0}},
+		{8.1758, [3]byte{0, 0, 0}},
+		{440 * 1.0293022366, [3]byte{69, 0x40, 0}}, // 50 cents above A4
+		{0, [3]byte{0x7F, 0x7F, 0x7F}},
+		{100000, [3]byte{0x7F, 0x7F, 0x7E}},
+	}
+	for _, test := range tests {
+		if got := mtsFrequency(test.frequency); got != test.want {
+			t.Errorf("mtsFrequency(%f) = %X, want %X", test.frequency, got, test.want)
+		}
+	}
+}
+
+func TestNewBulkTuningDump(t *testing.T) {
+	e, err := NewBulkTuningDump(AllDevices, 3, "Just C", JustIntonation(60))
+	if err != nil {
+		t.Fatalf("NewBulkTuningDump failed: %v", err)
+	}
+	// 5 header bytes, a 16 byte name, 128 keys with 3 bytes each, a checksum and F7
+	if len(e.Data) != 5+16+128*3+2 {
+		t.Fatalf("Bulk tuning dump is %d bytes, want %d", len(e.Data), 5+16+128*3+2)
+	}
+	if !bytes.Equal(e.Data[:5], []byte{0x7E, 0x7F, 0x08, 0x01, 3}) {
+		t.Errorf("Bulk tuning dump header = %X, want 7E 7F 08 01 03", e.Data[:5])
+	}
+	if name := string(e.Data[5:21]); name != "Just C          " {
+		t.Errorf("Bulk tuning dump name = %q", name)
+	}
+	var checksum byte
+	for _, b := range e.Data[:len(e.Data)-2] {
+		checksum ^= b
+	}
+	if e.Data[len(e.Data)-2] != checksum&0x7F {
+		t.Errorf("Bulk tuning dump checksum = %X, want %X", e.Data[len(e.Data)-2], checksum&0x7F)
+	}
+	// The just major third above middle C is 13.69 cents below E4
+	e4 := e.Data[21+64*3 : 21+65*3]
+	if e4[0] != 63 || e4[1] != 0x6E {
+		t.Errorf("Tuning of E4 = %X, want 3F 6E xx", e4)
+	}
+}
+
+func TestNewSingleNoteTuningChange(t *testing.T) {
+	changes := []NoteTuning{{Key: 60, Frequency: 261.6256}, {Key: 61, Frequency: 0}}
+	e, err := NewSingleNoteTuningChange(AllDevices, 0, 1, changes, true)
+	if err != nil {
+		t.Fatalf("NewSingleNoteTuningChange failed: %v", err)
+	}
+	want := []byte{0x7F, 0x7F, 0x08, 0x02, 1, 2, 60, 60, 0, 0, 61, 0x7F, 0x7F, 0x7F, 0xF7}
+	if !bytes.Equal(e.Data, want) {
+		t.Errorf("Real-time single note tuning change = %X, want %X", e.Data, want)
+	}
+	e, err = NewSingleNoteTuningChange(AllDevices, 2, 1, changes[:1], false)
+	if err != nil {
+		t.Fatalf("NewSingleNoteTuningChange failed: %v", err)
+	}
+	if !bytes.Equal(e.Data[:7], []byte{0x7E, 0x7F, 0x08, 0x07, 2, 1, 1}) {
+		t.Errorf("Non-real-time single note tuning change header = %X", e.Data[:7])
+	}
+}
+
+func TestNewScaleOctaveTuning(t *testing.T) {
+	cents := [12]float64{0, 0, 0, 0, -14, 0, 0, 2, 0, -16, 0, 0}
+	e, err := NewScaleOctaveTuning(AllDevices, []uint8{1, 8, 16}, cents, false, false)
+	if err != nil {
+		t.Fatalf("NewScaleOctaveTuning failed: %v", err)
+	}
+	want := []byte{0x7E, 0x7F, 0x08, 0x08, 0x02, 0x01, 0x01, 64, 64, 64, 64, 50, 64, 64, 66, 64, 48, 64, 64, 0xF7}
+	if !bytes.Equal(e.Data, want) {
+		t.Errorf("1 byte scale/octave tuning = %X, want %X", e.Data, want)
+	}
+	e, err = NewScaleOctaveTuning(AllDevices, []uint8{1}, [12]float64{100, -100}, true, true)
+	if err != nil {
+		t.Fatalf("NewScaleOctaveTuning failed: %v", err)
+	}
+	if !bytes.Equal(e.Data[7:13], []byte{0x7F, 0x7F, 0x00, 0x00, 0x40, 0x00}) {
+		t.Errorf("2 byte scale/octave tuning values = %X, want 7F 7F 00 00 40 00", e.Data[7:13])
+	}
+	if _, err := NewScaleOctaveTuning(AllDevices, []uint8{17}, cents, false, false); err == nil {
+		t.Error("NewScaleOctaveTuning should fail for channel 17")
+	}
+}
+
+func TestAddNoteWithTuningDump(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	m.Tuning = Meantone(60)
+	track := NewTrack()
+	m.AddTrack(track)
+	for _, number := range []int{60, 64} {
+		if err := m.AddNote(track, &Note{Pitch: &Pitch{Number: number}, Duration: time.Second, Channel: 1}); err != nil {
+			t.Fatalf("AddNote failed: %v", err)
+		}
+	}
+	dumps := 0
+	for _, e := range track.Events {
+		if e.Type == EventSysEx {
+			dumps++
+		}
+		if e.Type == PitchBend {
+			t.Error("AddNote should not add pitch bends without PitchBendRange")
+		}
+	}
+	if dumps != 1 {
+		t.Errorf("AddNote added %d tuning dumps, want 1", dumps)
+	}
+
+	// The tuning program and bank are selected once on each channel, with RPN 3 and RPN 4
+	if err := m.AddNote(track, &Note{Pitch: &Pitch{Number: 67}, Duration: time.Second, Channel: 2}); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	selected := make(map[uint8][]uint8)
+	for _, e := range track.Events {
+		if e.Type == ControlChange && e.Data[0] == controllerRPNLSB && e.Data[1] != 127 {
+			selected[e.Channel] = append(selected[e.Channel], e.Data[1])
+		}
+	}
+	for _, channel := range []uint8{1, 2} {
+		if s := selected[channel]; len(s) != 2 || s[0] != rpnTuningBank || s[1] != rpnTuningProgram {
+			t.Errorf("Channel %d selected the RPNs %v, want the tuning bank and the tuning program once", channel, s)
+		}
+	}
+}
+
+func TestAddNoteWithTuningDumpInvalidNote(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	m.Tuning = Meantone(60)
+	track := NewTrack()
+	m.AddTrack(track)
+	for _, note := range []*Note{
+		{Pitch: &Pitch{Number: 60}, Duration: time.Second, Channel: 0},
+		{Pitch: &Pitch{Number: 200}, Duration: time.Second, Channel: 1},
+	} {
+		if err := m.AddNote(track, note); err == nil {
+			t.Errorf("AddNote should fail for %+v", note)
+		}
+	}
+	if len(track.Events) != 0 {
+		t.Fatalf("A rejected note should not add events, got %d", len(track.Events))
+	}
+	if err := m.AddNote(track, &Note{Pitch: &Pitch{Number: 60}, Duration: time.Second, Channel: 1}); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	if track.Events[0].Type != EventSysEx {
+		t.Errorf("The first valid note should add the tuning dump first, got %+v", track.Events[0])
+	}
+}
@@ -0,0 +1,119 @@
+package midi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// OctaveConvention selects the octave number of middle C (MIDI note 60) in note names
+type OctaveConvention int
+
+const (
+	// Scientific pitch notation, where middle C is C4
+	Scientific OctaveConvention = iota
+	// Yamaha notation, where middle C is C3
+	Yamaha
+)
+
+// NoteNameOptions are the conventions used when parsing note names
+type NoteNameOptions struct {
+	Octaves OctaveConvention
+	German  bool // B is B flat and H is B natural, and "is" and "es" can be used as accidentals
+}
+
+// letterPitches are the pitch classes of the note letters
+var letterPitches = map[byte]int{
+	'C': 0,
+	'D': 2,
+	'E': 4,
+	'F': 5,
+	'G': 7,
+	'A': 9,
+	'B': 11,
+}
+
+// ParseNoteName parses a note name in scientific pitch notation, like "C4", "F#3",
+// "Bbb2", "ex5" or "C-1". The letter is not case sensitive, and H can be used for B.
+// An error is returned if the note is outside of the MIDI note range, from C-1 to G9.
+func ParseNoteName(name string) (Pitch, error) {
+	return ParseNoteNameWith(name, NoteNameOptions{})
+}
+
+// ParseNoteNameWith parses a note name, with the given conventions
+func ParseNoteNameWith(name string, options NoteNameOptions) (Pitch, error) {
+	s := strings.TrimSpace(name)
+	if s == "" {
+		return Pitch{}, fmt.Errorf("empty note name")
+	}
+
+	// The note letter
+	p := Pitch{Letter: strings.ToUpper(s[:1])[0]}
+	s = s[1:]
+	switch {
+	case p.Letter == 'H':
+		p.Letter = 'B'
+	case p.Letter == 'B' && options.German:
+		p.Accidental = -1
+	}
+	pitchClass, ok := letterPitches[p.Letter]
+	if !ok {
+		return Pitch{}, fmt.Errorf("invalid note name %q: unknown note letter", name)
+	}
+
+	// The accidentals
+	for s != "" {
+		if options.German && strings.HasPrefix(s, "is") {
+			p.Accidental++
+			s = s[2:]
+			continue
+		}
+		if options.German && strings.HasPrefix(s, "es") {
+			p.Accidental--
+			s = s[2:]
+			continue
+		}
+		if options.German && s[0] == 's' && (p.Letter == 'A' || p.Letter == 'E') && p.Accidental == 0 {
+			// As and Es
+			p.Accidental--
+			s = s[1:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		switch r {
+		case '#', '♯':
+			p.Accidental++
+		case 'x', '𝄪':
+			p.Accidental += 2
+		case 'b', '♭':
+			p.Accidental--
+		case '𝄫':
+			p.Accidental -= 2
+		default:
+			size = 0
+		}
+		if size == 0 {
+			break
+		}
+		s = s[size:]
+	}
+
+	// The octave
+	if s == "" {
+		return Pitch{}, fmt.Errorf("invalid note name %q: missing octave", name)
+	}
+	octave, err := strconv.Atoi(s)
+	if err != nil || len(strings.TrimPrefix(s, "-")) > 2 || strings.HasPrefix(s, "+") {
+		return Pitch{}, fmt.Errorf("invalid note name %q: invalid octave %q", name, s)
+	}
+	if options.Octaves == Yamaha {
+		octave++
+	}
+
+	p.Number = 12*(octave+1) + pitchClass + p.Accidental
+	if p.Number < 0 || p.Number > 127 {
+		return Pitch{}, fmt.Errorf("invalid note name %q: outside of the MIDI note range", name)
+	}
+	return p, nil
+}
@@ -0,0 +1,72 @@
+package midi
+
+import "testing"
+
+func TestParseNoteName(t *testing.T) {
+	tests := []struct {
+		name   string
+		number int
+	}{
+		{"C4", 60},
+		{"c4", 60},
+		{"A4", 69},
+		{"C#4", 61},
+		{"Db4", 61},
+		{"Cb4", 59},
+		{"E#4", 65},
+		{"Bbb3", 57},
+		{"bb3", 58},
+		{"Fx4", 67},
+		{"F##4", 67},
+		{"H3", 59},
+		{"C-1", 0},
+		{"G9", 127},
+		{"E♭4", 63},
+	}
+	for _, test := range tests {
+		p, err := ParseNoteName(test.name)
+		if err != nil {
+			t.Errorf("ParseNoteName(%q) failed: %v", test.name, err)
+			continue
+		}
+		if p.Number != test.number {
+			t.Errorf("ParseNoteName(%q) = %d, want %d", test.name, p.Number, test.number)
+		}
+	}
+
+	p, _ := ParseNoteName("Bbb3")
+	if p.Letter != 'B' || p.Accidental != -2 {
+		t.Errorf("ParseNoteName(\"Bbb3\") is spelled %c with %d accidentals, want B with -2", p.Letter, p.Accidental)
+	}
+
+	for _, name := range []string{"", "C", "X4", "C#", "C4.5", "C+4", "C123", "Cq4", "C10", "G#9", "Cb-1"} {
+		if _, err := ParseNoteName(name); err == nil {
+			t.Errorf("ParseNoteName(%q) should fail", name)
+		}
+	}
+}
+
+func TestParseNoteNameWith(t *testing.T) {
+	p, err := ParseNoteNameWith("C3", NoteNameOptions{Octaves: Yamaha})
+	if err != nil || p.Number != 60 {
+		t.Errorf("ParseNoteNameWith(\"C3\", Yamaha) = %d, %v, want 60, nil", p.Number, err)
+	}
+	german := NoteNameOptions{German: true}
+	for name, number := range map[string]int{"B3": 58, "H3": 59, "Fis4": 66, "Es4": 63, "As4": 68} {
+		p, err := ParseNoteNameWith(name, german)
+		if err != nil || p.Number != number {
+			t.Errorf("ParseNoteNameWith(%q, German) = %d, %v, want %d, nil", name, p.Number, err, number)
+		}
+	}
+}
+
+func TestAddNoteFromNoteStringError(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	track := NewTrack()
+	if err := m.AddNoteFromNoteString(track, "X4:1s", 0, 0); err == nil {
+		t.Error("AddNoteFromNoteString should fail for an invalid note name")
+	}
+	if len(track.NoteMap) != 0 {
+		t.Error("AddNoteFromNoteString should not add a note with an invalid note name")
+	}
+}
@@ -0,0 +1,104 @@
+package midi
+
+import (
+	"sort"
+)
+
+// Notes returns the notes in a track, by pairing each "note on" event with the
+// next "note off" event for the same channel and key. Overlapping notes with the
+// same key are ended in the order they started, and notes that never end last
+// until the last event in the track. The notes are sorted by their start tick,
+// and have the frequency of the key in equal temperament, with A4 at 440Hz.
+// The times of the notes are only set in ticks, since a track has no tempo.
+func (t *Track) Notes() []*Note {
+	var notes []*Note
+	for _, pair := range notePairs(t.Events) {
+		on := pair.on
+		pitch := NewPitch(int(on.Data[0]))
+		note := &Note{
+			Pitch:         &pitch,
+			Frequency:     midiNumberToFrequency(float64(on.Data[0])),
+			Velocity:      on.Data[1],
+			Channel:       on.Channel,
+			Program:       pair.program,
+			Tick:          on.Tick,
+			DurationTicks: pair.end - on.Tick,
+		}
+		if on.Channel == DrumChannel {
+			note.Drum = DrumName(on.Data[0])
+		}
+		notes = append(notes, note)
+	}
+	return notes
+}
+
+// notePair is a "note on" event with the "note off" event that ends it
+type notePair struct {
+	on      *Event
+	off     *Event // nil if the note never ends
+	end     uint32 // the tick of the "note off" event, or of the last event if the note never ends
+	program uint8  // the program of the channel when the note starts
+}
+
+// notePairs pairs each "note on" event with the next "note off" event for the
+// same channel and key, in the order the notes started. The pairs are sorted by
+// the start of the notes. Events at the same tick are kept in the order of the
+// track, so that a note that starts and ends at the same tick is paired, while a
+// "note off" event still ends an earlier note with the same key first.
+func notePairs(events []*Event) []*notePair {
+	var (
+		pairs    []*notePair
+		lastTick uint32
+		open     = make(map[[2]uint8][]*notePair) // the notes that have not ended, by channel and key
+		programs = make(map[uint8]uint8)
+	)
+	sorted := make([]*Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Tick < sorted[j].Tick
+	})
+	for _, e := range sorted {
+		lastTick = e.Tick
+		switch {
+		case e.IsNoteOff():
+			if len(e.Data) < 1 {
+				continue
+			}
+			key := [2]uint8{e.Channel, e.Data[0]}
+			if playing := open[key]; len(playing) > 0 {
+				playing[0].off, playing[0].end = e, e.Tick
+				open[key] = playing[1:]
+			}
+		case e.Type == NoteOn && len(e.Data) > 1:
+			program, ok := programs[e.Channel]
+			if !ok {
+				program = e.Program
+			}
+			pair := &notePair{on: e, program: program}
+			pairs = append(pairs, pair)
+			key := [2]uint8{e.Channel, e.Data[0]}
+			open[key] = append(open[key], pair)
+		case e.Type == ProgramChange && len(e.Data) > 0:
+			programs[e.Channel] = e.Data[0]
+		}
+	}
+	for _, playing := range open {
+		for _, pair := range playing {
+			pair.end = lastTick
+		}
+	}
+	return pairs
+}
+
+// Notes returns the notes in a track, like Track.Notes, with the start times and
+// durations of the notes converted from ticks with the tempo map of the song
+func (m *MIDI) Notes(t *Track) []*Note {
+	tempoMap := m.TempoMap()
+	notes := t.Notes()
+	for _, note := range notes {
+		start := tempoMap.TicksToDuration(note.Tick)
+		note.EventDelay = start
+		note.Duration = tempoMap.TicksToDuration(note.Tick+note.DurationTicks) - start
+	}
+	return notes
+}
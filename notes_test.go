@@ -0,0 +1,107 @@
+package midi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackNotes(t *testing.T) {
+	track := NewTrack()
+	must := func(e *Event, err error) *Event {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	track.AddEventAt(0, must(NewProgramChange(2, 24)))
+	track.AddEventAt(0, must(NewNoteOn(2, 60, 100)))
+	track.AddEventAt(96, must(NewNoteOn(2, 60, 0))) // "note on" with velocity 0 ends the note
+	// Overlapping notes with the same key end in the order they started
+	track.AddEventAt(96, must(NewNoteOn(2, 64, 80)))
+	track.AddEventAt(144, must(NewNoteOn(2, 64, 90)))
+	track.AddEventAt(192, must(NewNoteOff(2, 64, 0)))
+	track.AddEventAt(240, must(NewNoteOff(2, 64, 0)))
+	track.AddEventAt(0, must(NewNoteOn(DrumChannel, 42, 70)))
+	track.AddEventAt(24, must(NewNoteOff(DrumChannel, 42, 0)))
+	// A note that never ends
+	track.AddEventAt(288, must(NewNoteOn(3, 72, 50)))
+	track.AddEventAt(384, NewEndOfTrack())
+
+	notes := track.Notes()
+	want := []struct {
+		tick, duration    uint32
+		channel, key, vel uint8
+		program           uint8
+	}{
+		{0, 96, 2, 60, 100, 24},
+		{0, 24, DrumChannel, 42, 70, 0},
+		{96, 96, 2, 64, 80, 24},
+		{144, 96, 2, 64, 90, 24},
+		{288, 96, 3, 72, 50, 0},
+	}
+	if len(notes) != len(want) {
+		t.Fatalf("got %d notes, want %d", len(notes), len(want))
+	}
+	for i, w := range want {
+		n := notes[i]
+		if n.Tick != w.tick || n.DurationTicks != w.duration || n.Channel != w.channel ||
+			n.Pitch.Number != int(w.key) || n.Velocity != w.vel || n.Program != w.program {
+			t.Errorf("note %d: got tick %d, duration %d, channel %d, key %d, velocity %d, program %d, want %+v",
+				i, n.Tick, n.DurationTicks, n.Channel, n.Pitch.Number, n.Velocity, n.Program, w)
+		}
+	}
+	if notes[1].Drum != "Closed Hi-Hat" {
+		t.Errorf("drum = %q, want \"Closed Hi-Hat\"", notes[1].Drum)
+	}
+}
+
+func TestMIDINotes(t *testing.T) {
+	m := NewMIDI(0, 96, 120)
+	track := NewTrack()
+	input := []*Note{
+		{Frequency: 440, Duration: time.Second, Velocity: 100, Channel: 1, Program: 5},
+		{Frequency: 261.63, Duration: time.Second / 2, Velocity: 64, Channel: 1, Program: 5, EventDelay: time.Second / 2},
+	}
+	for _, note := range input {
+		if err := m.AddNote(track, note); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notes := m.Notes(track)
+	if len(notes) != 2 {
+		t.Fatalf("got %d notes, want 2", len(notes))
+	}
+	for i, note := range notes {
+		if note.EventDelay != input[i].EventDelay || note.Duration != input[i].Duration || note.Program != 5 {
+			t.Errorf("note %d starts at %v and lasts %v with program %d, want %v, %v and 5",
+				i, note.EventDelay, note.Duration, note.Program, input[i].EventDelay, input[i].Duration)
+		}
+	}
+	if notes[0].Pitch.String() != "A4" || notes[1].Pitch.String() != "C4" {
+		t.Errorf("pitches = %s and %s, want A4 and C4", notes[0].Pitch, notes[1].Pitch)
+	}
+}
+
+func TestTrackNotesSameTick(t *testing.T) {
+	track := NewTrack()
+	// A note that starts and ends at the same tick, in the order it was played
+	track.AddEventAt(0, &Event{Type: NoteOn, Channel: 1, Data: []byte{69, 100}})
+	track.AddEventAt(0, &Event{Type: NoteOff, Channel: 1, Data: []byte{69, 0}})
+	// A repeated note, where the next note starts before the previous one ends in the track
+	track.AddEventAt(96, &Event{Type: NoteOn, Channel: 1, Data: []byte{60, 100}})
+	track.AddEventAt(192, &Event{Type: NoteOn, Channel: 1, Data: []byte{60, 90}})
+	track.AddEventAt(192, &Event{Type: NoteOff, Channel: 1, Data: []byte{60, 0}})
+	track.AddEventAt(288, &Event{Type: NoteOff, Channel: 1, Data: []byte{60, 0}})
+	track.AddEventAt(384, NewEndOfTrack())
+
+	notes := track.Notes()
+	want := [][2]uint32{{0, 0}, {96, 96}, {192, 96}}
+	if len(notes) != len(want) {
+		t.Fatalf("got %d notes, want %d", len(notes), len(want))
+	}
+	for i, w := range want {
+		if notes[i].Tick != w[0] || notes[i].DurationTicks != w[1] {
+			t.Errorf("note %d: got tick %d and duration %d, want %d and %d", i, notes[i].Tick, notes[i].DurationTicks, w[0], w[1])
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package midi
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Pitch is a musical pitch, as a MIDI note number with an optional spelling and cents offset
+type Pitch struct {
+	Number     int     // The MIDI note number, where 60 is middle C. It may be outside of the MIDI range.
+	Letter     byte    // The letter of the note name, from 'A' to 'G', or 0 if the spelling is unknown
+	Accidental int     // The number of sharps, or flats if negative, in the spelling
+	Cents      float64 // The offset from the MIDI note, in cents
+}
+
+// Interval is the distance between two pitches
+type Interval struct {
+	Semitones int     // The number of semitones
+	Steps     int     // The number of note letters, like 2 for a third and 4 for a fifth
+	Cents     float64 // The offset in cents, in addition to the semitones
+}
+
+// Common intervals, with the number of semitones and the number of note letters
+var (
+	Unison        = Interval{Semitones: 0, Steps: 0}
+	MinorSecond   = Interval{Semitones: 1, Steps: 1}
+	MajorSecond   = Interval{Semitones: 2, Steps: 1}
+	MinorThird    = Interval{Semitones: 3, Steps: 2}
+	MajorThird    = Interval{Semitones: 4, Steps: 2}
+	PerfectFourth = Interval{Semitones: 5, Steps: 3}
+	Tritone       = Interval{Semitones: 6, Steps: 3}
+	PerfectFifth  = Interval{Semitones: 7, Steps: 4}
+	MinorSixth    = Interval{Semitones: 8, Steps: 5}
+	MajorSixth    = Interval{Semitones: 9, Steps: 5}
+	MinorSeventh  = Interval{Semitones: 10, Steps: 6}
+	MajorSeventh  = Interval{Semitones: 11, Steps: 6}
+	Octave        = Interval{Semitones: 12, Steps: 7}
+)
+
+// noteLetters are the note letters, in order from C
+const noteLetters = "CDEFGAB"
+
+// sharpSpellings are the letters and accidentals used for pitches without a spelling
+var sharpSpellings = [12]struct {
+	letter     byte
+	accidental int
+}{
+	{'C', 0}, {'C', 1}, {'D', 0}, {'D', 1}, {'E', 0}, {'F', 0},
+	{'F', 1}, {'G', 0}, {'G', 1}, {'A', 0}, {'A', 1}, {'B', 0},
+}
+
+// NewPitch creates a new Pitch from a MIDI note number, without a spelling
+func NewPitch(number int) Pitch {
+	return Pitch{Number: number}
+}
+
+// spelled returns the pitch with a spelling, using sharps if it has none
+func (p Pitch) spelled() Pitch {
+	if p.Letter == 0 {
+		spelling := sharpSpellings[mod(p.Number, 12)]
+		p.Letter, p.Accidental = spelling.letter, spelling.accidental
+	}
+	return p
+}
+
+// Octave returns the octave number of the pitch in scientific pitch notation,
+// following the spelling, so that B#3 and C4 are in different octaves
+func (p Pitch) Octave() int {
+	p = p.spelled()
+	natural := p.Number - p.Accidental - letterPitches[p.Letter]
+	return floorDiv(natural, 12) - 1
+}
+
+// String returns the name of the pitch, like "C#4" or "Bb3+14c"
+func (p Pitch) String() string {
+	s := p.spelled()
+	var sb strings.Builder
+	sb.WriteByte(s.Letter)
+	if s.Accidental > 0 {
+		sb.WriteString(strings.Repeat("#", s.Accidental))
+	} else if s.Accidental < 0 {
+		sb.WriteString(strings.Repeat("b", -s.Accidental))
+	}
+	fmt.Fprintf(&sb, "%d", p.Octave())
+	if p.Cents != 0 {
+		fmt.Fprintf(&sb, "%+gc", math.Round(p.Cents*100)/100)
+	}
+	return sb.String()
+}
+
+// Frequency returns the frequency of the pitch in the given tuning.
+// If the tuning is nil, A4 is 440Hz with equal temperament.
+func (p Pitch) Frequency(tuning Tuning) float64 {
+	var frequency float64
+	if tuning == nil {
+		frequency = midiNumberToFrequency(float64(p.Number))
+	} else {
+		frequency = tuning.Frequency(p.Number)
+	}
+	return frequency * math.Pow(2, p.Cents/1200)
+}
+
+// MIDI returns the MIDI note number of the pitch, if it is from 0 to 127
+func (p Pitch) MIDI() (uint8, error) {
+	if p.Number < 0 || p.Number > 127 {
+		return 0, fmt.Errorf("pitch %s is outside of the MIDI note range", p)
+	}
+	return uint8(p.Number), nil
+}
+
+// Transpose returns the pitch moved by the given number of semitones.
+// The spelling is not kept, since it is ambiguous.
+func (p Pitch) Transpose(semitones int) Pitch {
+	return Pitch{Number: p.Number + semitones, Cents: p.Cents}
+}
+
+// Add returns the pitch moved by an interval. If the pitch has a spelling, the
+// spelling of the new pitch follows the interval, so that E plus a minor third is G.
+func (p Pitch) Add(i Interval) Pitch {
+	result := Pitch{Number: p.Number + i.Semitones, Cents: p.Cents + i.Cents}
+	if p.Letter == 0 {
+		return result
+	}
+	index := strings.IndexByte(noteLetters, p.Letter) + i.Steps
+	result.Letter = noteLetters[mod(index, 7)]
+	// The accidental is the distance from the nearest natural note with the new letter
+	result.Accidental = mod(result.Number-letterPitches[result.Letter]+6, 12) - 6
+	return result
+}
+
+// Interval returns the interval from this pitch to another pitch
+func (p Pitch) Interval(to Pitch) Interval {
+	i := Interval{Semitones: to.Number - p.Number, Cents: to.Cents - p.Cents}
+	if p.Letter != 0 && to.Letter != 0 {
+		i.Steps = to.diatonicIndex() - p.diatonicIndex()
+	} else {
+		i.Steps = defaultSteps(i.Semitones)
+	}
+	return i
+}
+
+// diatonicIndex returns the number of note letters from C-1 to the spelled pitch
+func (p Pitch) diatonicIndex() int {
+	return (p.Octave()+1)*7 + strings.IndexByte(noteLetters, p.spelled().Letter)
+}
+
+// defaultSteps returns the number of note letters for a number of semitones, as if spelled with sharps
+func defaultSteps(semitones int) int {
+	steps := [12]int{0, 0, 1, 1, 2, 3, 3, 4, 4, 5, 5, 6}
+	return floorDiv(semitones, 12)*7 + steps[mod(semitones, 12)]
+}
+
+// Invert returns the interval in the opposite direction
+func (i Interval) Invert() Interval {
+	return Interval{Semitones: -i.Semitones, Steps: -i.Steps, Cents: -i.Cents}
+}
+
+// Add returns the sum of two intervals
+func (i Interval) Add(other Interval) Interval {
+	return Interval{Semitones: i.Semitones + other.Semitones, Steps: i.Steps + other.Steps, Cents: i.Cents + other.Cents}
+}
+
+// mod returns a modulo b, where the result is never negative
+func mod(a, b int) int {
+	return ((a % b) + b) % b
+}
+
+// floorDiv returns a divided by b, rounded down
+func floorDiv(a, b int) int {
+	return (a - mod(a, b)) / b
+}
@@ -0,0 +1,106 @@
+package midi
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPitchString(t *testing.T) {
+	tests := map[string]string{
+		"C4":   "C4",
+		"Db4":  "Db4",
+		"B#3":  "B#3",
+		"Cb4":  "Cb4",
+		"Bbb3": "Bbb3",
+		"C-1":  "C-1",
+	}
+	for name, want := range tests {
+		p, err := ParseNoteName(name)
+		if err != nil {
+			t.Fatalf("ParseNoteName(%q) failed: %v", name, err)
+		}
+		if s := p.String(); s != want {
+			t.Errorf("String() of %q = %q, want %q", name, s, want)
+		}
+	}
+	if s := NewPitch(61).String(); s != "C#4" {
+		t.Errorf("NewPitch(61).String() = %q, want \"C#4\"", s)
+	}
+	if s := (Pitch{Number: 69, Cents: -14}).String(); s != "A4-14c" {
+		t.Errorf("String() with cents = %q, want \"A4-14c\"", s)
+	}
+}
+
+func TestPitchFrequency(t *testing.T) {
+	a4 := NewPitch(69)
+	if f := a4.Frequency(nil); math.Abs(f-440) > 0.001 {
+		t.Errorf("Frequency(nil) of A4 = %f, want 440", f)
+	}
+	if f := a4.Frequency(EqualTemperament{A4: 415}); math.Abs(f-415) > 0.001 {
+		t.Errorf("Frequency of A4 with A4 at 415Hz = %f, want 415", f)
+	}
+	a4.Cents = 1200
+	if f := a4.Frequency(nil); math.Abs(f-880) > 0.001 {
+		t.Errorf("Frequency(nil) of A4 plus 1200 cents = %f, want 880", f)
+	}
+}
+
+func TestPitchIntervals(t *testing.T) {
+	e4, _ := ParseNoteName("E4")
+	if g := e4.Add(MinorThird); g.String() != "G4" {
+		t.Errorf("E4 plus a minor third = %s, want G4", g)
+	}
+	if gs := e4.Add(MajorThird); gs.String() != "G#4" {
+		t.Errorf("E4 plus a major third = %s, want G#4", gs)
+	}
+	if b := e4.Add(PerfectFifth.Invert()); b.String() != "A3" {
+		t.Errorf("E4 minus a perfect fifth = %s, want A3", b)
+	}
+	eb4, _ := ParseNoteName("Eb4")
+	if bb := eb4.Add(PerfectFifth); bb.String() != "Bb4" {
+		t.Errorf("Eb4 plus a perfect fifth = %s, want Bb4", bb)
+	}
+	c4, _ := ParseNoteName("C4")
+	if i := c4.Interval(eb4); i != MinorThird {
+		t.Errorf("Interval from C4 to Eb4 = %+v, want a minor third", i)
+	}
+	ds4, _ := ParseNoteName("D#4")
+	if i := c4.Interval(ds4); i.Semitones != 3 || i.Steps != 1 {
+		t.Errorf("Interval from C4 to D#4 = %+v, want 3 semitones and 1 step", i)
+	}
+	if p := c4.Transpose(-13); p.Number != 47 || p.String() != "B2" {
+		t.Errorf("C4 transposed down 13 semitones = %s, want B2", p)
+	}
+	if i := MajorThird.Add(MinorThird); i != PerfectFifth {
+		t.Errorf("A major third plus a minor third = %+v, want a perfect fifth", i)
+	}
+}
+
+func TestAddNoteWithPitch(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	m.PitchBendRange = 2
+	track := NewTrack()
+	m.AddTrack(track)
+
+	if err := m.AddNote(track, &Note{Pitch: &Pitch{Number: 60, Cents: 75}, Duration: time.Second, Channel: 1}); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	for _, e := range track.Events {
+		switch e.Type {
+		case EventNoteOn:
+			if e.Data[0] != 61 {
+				t.Errorf("Note on for C4 plus 75 cents = %d, want 61", e.Data[0])
+			}
+		case PitchBend:
+			// 25 cents below C#4, with a range of 2 semitones
+			if value, _ := e.PitchBendValue(); value != -1024 {
+				t.Errorf("Pitch bend for C4 plus 75 cents = %d, want -1024", value)
+			}
+		}
+	}
+
+	if err := m.AddNote(track, &Note{Pitch: &Pitch{Number: 128}, Duration: time.Second, Channel: 1}); err == nil {
+		t.Error("AddNote should fail for a pitch outside of the MIDI range")
+	}
+}
@@ -0,0 +1,409 @@
+package midi
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Output receives MIDI events, like a MIDI device or a software synthesizer
+type Output interface {
+	Send(e *Event) error
+}
+
+// Input receives MIDI events from a MIDI device
+type Input interface {
+	Receive() (*Event, error)
+}
+
+// Clock is the time source of a Player
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is a Clock that uses the time package
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// ErrPlaying is returned by Player.Play if the player is already playing
+var ErrPlaying = errors.New("the player is already playing")
+
+// Player plays the events in all the tracks of a MIDI song in real time, by
+// sending them to an Output at the right time, following the tempo map
+type Player struct {
+	// Clock is the time source. It is the system clock by default.
+	Clock Clock
+
+	out      Output
+	events   []*Event
+	tempoMap *TempoMap
+
+	mu         sync.Mutex
+	index      int       // the next event to send
+	anchorTick uint32    // the position at the anchor time
+	anchorTime time.Time // the time when the position was at the anchor tick
+	lastTick   uint32    // the tick of the last event that was sent, or the position after moving
+	scale      float64   // the tempo scale
+	playing    bool
+	paused     bool
+	stopped    bool
+	looping    bool
+	loopStart  uint32
+	loopEnd    uint32
+	generation int               // incremented when the position or the timing changes
+	active     map[[2]uint8]bool // the notes that are playing, by channel and key
+	pending    []*Event          // events that should be sent before the next event
+	wake       chan struct{}     // signals the playing goroutine that something changed
+}
+
+// NewPlayer creates a new Player for a MIDI song, that sends the events to an Output
+func NewPlayer(m *MIDI, out Output) *Player {
+	return &Player{
+		Clock:    systemClock{},
+		out:      out,
+		events:   slices.Collect(m.All()),
+		tempoMap: m.TempoMap(),
+		scale:    1,
+		active:   make(map[[2]uint8]bool),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Play plays the song from the current position, until the end of the song, until
+// Stop is called or until the context is cancelled. Notes that are playing are
+// stopped when playback is paused, stopped, moved or cancelled. At the end of the
+// song, the position moves back to the start.
+func (p *Player) Play(ctx context.Context) error {
+	p.mu.Lock()
+	if p.playing {
+		p.mu.Unlock()
+		return ErrPlaying
+	}
+	p.playing, p.stopped = true, false
+	p.anchorTime = p.Clock.Now()
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.playing = false
+		p.mu.Unlock()
+	}()
+
+	for {
+		p.mu.Lock()
+		if pending := p.takePending(); len(pending) > 0 {
+			p.mu.Unlock()
+			if err := p.sendAll(pending); err != nil {
+				return err
+			}
+			continue
+		}
+		if p.stopped {
+			p.mu.Unlock()
+			return nil
+		}
+		if ctx.Err() != nil {
+			p.mu.Unlock()
+			return p.cancel(ctx)
+		}
+		if p.paused {
+			p.mu.Unlock()
+			select {
+			case <-p.wake:
+				continue
+			case <-ctx.Done():
+				return p.cancel(ctx)
+			}
+		}
+
+		tick, loopJump, done := p.next()
+		if done {
+			p.pending = p.noteOffs()
+			pending := p.takePending()
+			p.index, p.anchorTick, p.lastTick = 0, 0, 0
+			p.mu.Unlock()
+			return p.sendAll(pending)
+		}
+		generation := p.generation
+		wait := p.timeAt(tick).Sub(p.Clock.Now())
+		p.mu.Unlock()
+
+		if wait > 0 {
+			select {
+			case <-p.Clock.After(wait):
+			case <-p.wake:
+				continue
+			case <-ctx.Done():
+				return p.cancel(ctx)
+			}
+		}
+
+		p.mu.Lock()
+		if p.generation != generation {
+			// The position or the timing changed while waiting
+			p.mu.Unlock()
+			continue
+		}
+		if loopJump {
+			// Stop the notes that are playing and chase the state at the start of the loop, like SeekTick
+			anchorTime := p.timeAt(p.loopEnd)
+			p.pending = append(p.pending, p.noteOffs()...)
+			p.moveTo(p.loopStart)
+			p.pending = append(p.pending, p.chase(p.loopStart)...)
+			p.anchorTime = anchorTime
+			p.mu.Unlock()
+			continue
+		}
+		e := p.events[p.index]
+		p.index++
+		p.lastTick = e.Tick
+		p.trackNote(e)
+		p.mu.Unlock()
+
+		if err := p.out.Send(e); err != nil {
+			return err
+		}
+	}
+}
+
+// Start plays the song in a new goroutine. The returned channel receives the result of Play.
+func (p *Player) Start(ctx context.Context) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- p.Play(ctx)
+	}()
+	return result
+}
+
+// Pause pauses the playback, and keeps the position
+func (p *Player) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused {
+		return
+	}
+	p.anchorTick = p.currentTick()
+	p.paused = true
+	p.pending = append(p.pending, p.noteOffs()...)
+	p.changed()
+}
+
+// Resume continues the playback after Pause
+func (p *Player) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		return
+	}
+	p.paused = false
+	p.anchorTime = p.Clock.Now()
+	p.changed()
+}
+
+// Stop stops the playback, and moves the position to the start of the song
+func (p *Player) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	p.paused = false
+	p.pending = append(p.pending, p.noteOffs()...)
+	p.index, p.anchorTick, p.lastTick = 0, 0, 0
+	p.changed()
+}
+
+// Seek moves the position to the given time from the start of the song
+func (p *Player) Seek(d time.Duration) {
+	p.SeekTick(p.tempoMap.DurationToTicks(d))
+}
+
+// SeekTick moves the position to the given tick. The last program change and
+// the last value of each controller before the position are sent again.
+func (p *Player) SeekTick(tick uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = append(p.pending, p.noteOffs()...)
+	p.moveTo(tick)
+	p.pending = append(p.pending, p.chase(tick)...)
+	p.changed()
+}
+
+// SetLoop makes the playback jump from the end tick back to the start tick, until
+// the loop is cleared. At each jump, the notes that are playing are stopped, and the
+// program changes and controller values before the start tick are sent again.
+func (p *Player) SetLoop(start, end uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.looping = start < end
+	p.loopStart, p.loopEnd = start, end
+	p.changed()
+}
+
+// ClearLoop stops the looping that was set with SetLoop
+func (p *Player) ClearLoop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.looping = false
+	p.changed()
+}
+
+// SetTempoScale changes the speed of the playback, where 1 is the original tempo
+// and 2 is twice as fast
+func (p *Player) SetTempoScale(scale float64) {
+	if scale <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.anchorTick = p.currentTick()
+	p.anchorTime = p.Clock.Now()
+	p.scale = scale
+	p.changed()
+}
+
+// Position returns the current position, as the time from the start of the song
+func (p *Player) Position() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tempoMap.TicksToDuration(p.currentTick())
+}
+
+// next returns the tick of the next thing to do, which is sending the next
+// event or jumping back to the start of the loop, or done if the song is over
+func (p *Player) next() (tick uint32, loopJump, done bool) {
+	inLoop := p.looping && p.lastTick < p.loopEnd
+	if p.index < len(p.events) {
+		tick = p.events[p.index].Tick
+		if !inLoop || tick < p.loopEnd {
+			return tick, false, false
+		}
+	}
+	if inLoop {
+		return p.loopEnd, true, false
+	}
+	return 0, false, true
+}
+
+// currentTick returns the tick at the current time
+func (p *Player) currentTick() uint32 {
+	if !p.playing || p.paused {
+		return p.anchorTick
+	}
+	elapsed := time.Duration(float64(p.Clock.Now().Sub(p.anchorTime)) * p.scale)
+	return p.tempoMap.DurationToTicks(p.tempoMap.TicksToDuration(p.anchorTick) + elapsed)
+}
+
+// timeAt returns the clock time when the playback reaches the given tick
+func (p *Player) timeAt(tick uint32) time.Time {
+	songTime := p.tempoMap.TicksToDuration(tick) - p.tempoMap.TicksToDuration(p.anchorTick)
+	return p.anchorTime.Add(time.Duration(float64(songTime) / p.scale))
+}
+
+// moveTo moves the position to the given tick, from the current time
+func (p *Player) moveTo(tick uint32) {
+	p.index = sort.Search(len(p.events), func(i int) bool {
+		return p.events[i].Tick >= tick
+	})
+	p.anchorTick, p.lastTick = tick, tick
+	p.anchorTime = p.Clock.Now()
+	p.generation++
+}
+
+// chase returns the last program change and controller values for each channel before the tick
+func (p *Player) chase(tick uint32) []*Event {
+	var chased []*Event
+	latest := make(map[[3]uint8]int)
+	for i, e := range p.events {
+		if e.Tick >= tick {
+			break
+		}
+		switch e.Type {
+		case ProgramChange:
+			latest[[3]uint8{e.Type, e.Channel, 0}] = i
+		case ControlChange:
+			latest[[3]uint8{e.Type, e.Channel, e.Data[0]}] = i
+		}
+	}
+	indices := make([]int, 0, len(latest))
+	for _, i := range latest {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	for _, i := range indices {
+		chased = append(chased, p.events[i])
+	}
+	return chased
+}
+
+// trackNote keeps track of the notes that are playing
+func (p *Player) trackNote(e *Event) {
+	switch {
+	case e.IsNoteOff():
+		delete(p.active, [2]uint8{e.Channel, e.Data[0]})
+	case e.Type == NoteOn:
+		p.active[[2]uint8{e.Channel, e.Data[0]}] = true
+	}
+}
+
+// noteOffs returns "note off" events for all the notes that are playing
+func (p *Player) noteOffs() []*Event {
+	var events []*Event
+	for note := range p.active {
+		events = append(events, &Event{Type: NoteOff, Channel: note[0], Data: []byte{note[1], 0}})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Channel != events[j].Channel {
+			return events[i].Channel < events[j].Channel
+		}
+		return events[i].Data[0] < events[j].Data[0]
+	})
+	p.active = make(map[[2]uint8]bool)
+	return events
+}
+
+// takePending returns and clears the events that should be sent before the next event
+func (p *Player) takePending() []*Event {
+	pending := p.pending
+	p.pending = nil
+	return pending
+}
+
+// changed wakes up the playing goroutine, after the position or the timing changed
+func (p *Player) changed() {
+	p.generation++
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// cancel stops the notes that are playing, and returns the error of a cancelled context
+func (p *Player) cancel(ctx context.Context) error {
+	p.mu.Lock()
+	pending := append(p.takePending(), p.noteOffs()...)
+	p.mu.Unlock()
+	if err := p.sendAll(pending); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// sendAll sends events to the output
+func (p *Player) sendAll(events []*Event) error {
+	for _, e := range events {
+		if err := p.out.Send(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package midi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock where waiting moves the time forward at once
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// sent is an event that was sent to a recordingOutput, and when
+type sent struct {
+	at    time.Duration
+	event *Event
+}
+
+// recordingOutput records the events that are sent to it, with the time of the fake clock
+type recordingOutput struct {
+	clock  *fakeClock
+	start  time.Time
+	events []sent
+	onSend func(e *Event)
+}
+
+func (o *recordingOutput) Send(e *Event) error {
+	o.events = append(o.events, sent{o.clock.Now().Sub(o.start), e})
+	if o.onSend != nil {
+		o.onSend(e)
+	}
+	return nil
+}
+
+// newTestPlayer creates a player for a song with a quarter note at each of the
+// given keys, at 120 BPM, and a program change at the start
+func newTestPlayer(t *testing.T, keys ...uint8) (*Player, *recordingOutput) {
+	m := NewMIDI(1, 96, 120)
+	track := NewTrack()
+	pc, err := NewProgramChange(1, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	track.AddEventAt(0, pc)
+	for i, key := range keys {
+		on, err := NewNoteOn(1, key, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		off, err := NewNoteOff(1, key, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		track.AddEventAt(uint32(i)*96, on)
+		track.AddEventAt(uint32(i+1)*96, off)
+	}
+	m.AddTrack(track)
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	out := &recordingOutput{clock: clock, start: clock.now}
+	p := NewPlayer(m, out)
+	p.Clock = clock
+	return p, out
+}
+
+// noteOnTimes returns the keys and times of the "note on" events that were sent
+func noteOnTimes(out *recordingOutput) ([]uint8, []time.Duration) {
+	var keys []uint8
+	var times []time.Duration
+	for _, s := range out.events {
+		if s.event.Type == NoteOn && !s.event.IsNoteOff() {
+			keys = append(keys, s.event.Data[0])
+			times = append(times, s.at)
+		}
+	}
+	return keys, times
+}
+
+func TestPlayerTiming(t *testing.T) {
+	p, out := newTestPlayer(t, 60, 62, 64)
+	if err := p.Play(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.events) != 7 {
+		t.Fatalf("got %d events, want 7", len(out.events))
+	}
+	want := []time.Duration{0, 0, 500 * time.Millisecond, 500 * time.Millisecond, time.Second, time.Second, 1500 * time.Millisecond}
+	for i, s := range out.events {
+		if s.at != want[i] {
+			t.Errorf("event %d was sent at %v, want %v", i, s.at, want[i])
+		}
+	}
+	// At the same tick, the note off comes before the next note on
+	if !out.events[2].event.IsNoteOff() || out.events[3].event.IsNoteOff() {
+		t.Errorf("the note off should come before the note on at 500ms")
+	}
+	if p.Position() != 0 {
+		t.Errorf("position after the end = %v, want 0", p.Position())
+	}
+}
+
+func TestPlayerTempoScale(t *testing.T) {
+	p, out := newTestPlayer(t, 60, 62)
+	p.SetTempoScale(2)
+	if err := p.Play(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, times := noteOnTimes(out)
+	if len(times) != 2 || times[1] != 250*time.Millisecond {
+		t.Errorf("note times = %v, want [0s 250ms]", times)
+	}
+}
+
+func TestPlayerSeek(t *testing.T) {
+	p, out := newTestPlayer(t, 60, 62, 64)
+	p.Seek(time.Second)
+	if err := p.Play(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if out.events[0].event.Type != ProgramChange || out.events[0].event.Data[0] != 40 {
+		t.Errorf("the program change should be sent again after seeking, got %+v", out.events[0].event)
+	}
+	keys, times := noteOnTimes(out)
+	if len(keys) != 1 || keys[0] != 64 || times[0] != 0 {
+		t.Errorf("notes after seeking = %v at %v, want [64] at [0s]", keys, times)
+	}
+}
+
+func TestPlayerLoop(t *testing.T) {
+	p, out := newTestPlayer(t, 60, 62, 64)
+	p.SetLoop(96, 192)
+	count := 0
+	out.onSend = func(e *Event) {
+		if e.Type == NoteOn && !e.IsNoteOff() {
+			if count++; count == 4 {
+				p.Stop()
+			}
+		}
+	}
+	if err := p.Play(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	keys, times := noteOnTimes(out)
+	wantKeys := []uint8{60, 62, 62, 62}
+	wantTimes := []time.Duration{0, 500 * time.Millisecond, time.Second, 1500 * time.Millisecond}
+	for i := range wantKeys {
+		if i >= len(keys) || keys[i] != wantKeys[i] || times[i] != wantTimes[i] {
+			t.Fatalf("notes = %v at %v, want %v at %v", keys, times, wantKeys, wantTimes)
+		}
+	}
+	last := out.events[len(out.events)-1].event
+	if !last.IsNoteOff() || last.Data[0] != 62 {
+		t.Errorf("the playing note should be stopped, last event is %+v", last)
+	}
+	if p.Position() != 0 {
+		t.Errorf("position after Stop = %v, want 0", p.Position())
+	}
+}
+
+func TestPlayerLoopLongNote(t *testing.T) {
+	// A note that starts inside the loop and ends after it
+	m := NewMIDI(1, 96, 120)
+	track := NewTrack()
+	pc, err := NewProgramChange(1, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	track.AddEventAt(0, pc)
+	on, err := NewNoteOn(1, 60, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	off, err := NewNoteOff(1, 60, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	track.AddEventAt(48, on)
+	track.AddEventAt(384, off)
+	m.AddTrack(track)
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	out := &recordingOutput{clock: clock, start: clock.now}
+	p := NewPlayer(m, out)
+	p.Clock = clock
+	p.SetLoop(24, 192)
+	count := 0
+	out.onSend = func(e *Event) {
+		if e.Type == NoteOn && !e.IsNoteOff() {
+			if count++; count == 3 {
+				p.Stop()
+			}
+		}
+	}
+	if err := p.Play(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The note is stopped at the end of each pass, and the program before the loop is chased
+	var kinds []string
+	for _, s := range out.events {
+		switch {
+		case s.event.IsNoteOff():
+			kinds = append(kinds, "off")
+		case s.event.Type == NoteOn:
+			kinds = append(kinds, "on")
+		case s.event.Type == ProgramChange:
+			kinds = append(kinds, "program")
+		}
+	}
+	want := []string{"program", "on", "off", "program", "on", "off", "program", "on", "off"}
+	if len(kinds) != len(want) {
+		t.Fatalf("events = %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("events = %v, want %v", kinds, want)
+		}
+	}
+	_, times := noteOnTimes(out)
+	if times[1] != 1125*time.Millisecond || times[2] != 2*time.Second {
+		t.Errorf("note times = %v, want [250ms 1.125s 2s]", times)
+	}
+}
+
+func TestPlayerPause(t *testing.T) {
+	p, out := newTestPlayer(t, 60, 62)
+	paused := make(chan struct{})
+	out.onSend = func(e *Event) {
+		if e.Type == NoteOn && e.Data[0] == 60 {
+			p.Pause()
+			close(paused)
+		}
+	}
+	result := p.Start(context.Background())
+	<-paused
+	position := p.Position()
+	p.Resume()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	if position != 0 {
+		t.Errorf("position when paused = %v, want 0", position)
+	}
+	if !out.events[2].event.IsNoteOff() || out.events[2].event.Data[0] != 60 {
+		t.Errorf("the playing note should be stopped when pausing, got %+v", out.events[2].event)
+	}
+	keys, _ := noteOnTimes(out)
+	if len(keys) != 2 {
+		t.Errorf("notes = %v, want [60 62]", keys)
+	}
+}
+
+func TestPlayerCancel(t *testing.T) {
+	p, out := newTestPlayer(t, 60, 62)
+	ctx, cancel := context.WithCancel(context.Background())
+	out.onSend = func(e *Event) {
+		if e.Type == NoteOn {
+			cancel()
+		}
+	}
+	if err := p.Play(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Play returned %v, want context.Canceled", err)
+	}
+	last := out.events[len(out.events)-1].event
+	if !last.IsNoteOff() || last.Data[0] != 60 {
+		t.Errorf("the playing note should be stopped, last event is %+v", last)
+	}
+	if keys, _ := noteOnTimes(out); len(keys) != 1 {
+		t.Errorf("notes = %v, want [60]", keys)
+	}
+}
@@ -0,0 +1,129 @@
+package midi
+
+import (
+	"math"
+)
+
+// QuantizeOptions are the options of Track.QuantizeWith
+type QuantizeOptions struct {
+	// Lengths also moves the ends of the notes, so that the lengths are multiples
+	// of the grid. Otherwise the notes keep their lengths.
+	Lengths bool
+
+	// Window is the sensitivity, as the largest distance from a grid position that
+	// a note can have to be moved, as a fraction of the grid. 0 moves all notes.
+	Window float64
+}
+
+// GridTicks returns the length of a grid, like 16 for sixteenth notes, in ticks.
+// Triplet grids fit three notes in the time of two.
+func (m *MIDI) GridTicks(noteValue int, triplet bool) uint32 {
+	if noteValue <= 0 {
+		return 0
+	}
+	ticks := float64(m.Division) * 4 / float64(noteValue)
+	if triplet {
+		ticks = ticks * 2 / 3
+	}
+	return uint32(math.Round(ticks))
+}
+
+// Quantize moves the starts of the notes in a track towards the nearest grid
+// position. The grid is in ticks, and the strength is how far the notes are moved,
+// from 0 to 1. The swing is the position of every second grid position in percent of
+// two grid lengths, where 50 is straight and 66 is triplet swing. The notes keep their
+// lengths, and the events at the same tick and channel as a note, like program
+// changes and pitch bends, are moved with the note.
+func (t *Track) Quantize(grid uint32, strength, swing float64) {
+	t.QuantizeWith(grid, strength, swing, QuantizeOptions{})
+}
+
+// QuantizeWith quantizes the notes in a track, like Quantize, with options for
+// the note lengths and the sensitivity
+func (t *Track) QuantizeWith(grid uint32, strength, swing float64, options QuantizeOptions) {
+	if grid == 0 {
+		return
+	}
+	if swing <= 0 {
+		swing = 50
+	}
+	strength = math.Max(0, math.Min(1, strength))
+
+	t.moveNotes(func(pair *notePair) (int64, int64, bool) {
+		start := int64(pair.on.Tick)
+		target := swingGridPosition(start, int64(grid), swing)
+		if options.Window > 0 && math.Abs(float64(target-start)) > options.Window*float64(grid) {
+			return 0, 0, false
+		}
+		newStart := start + int64(math.Round(float64(target-start)*strength))
+		end := int64(pair.end) + newStart - start
+		if options.Lengths && pair.off != nil {
+			length := float64(int64(pair.end) - start)
+			quantized := math.Max(1, math.Round(length/float64(grid))) * float64(grid)
+			end = newStart + int64(math.Round(length+(quantized-length)*strength))
+		}
+		return newStart, end, true
+	})
+}
+
+// moveNotes moves the start and the end of each note in a track to the ticks returned
+// by move, unless it returns false. The other channel events at the start of a note,
+// like program changes and pitch bends, are moved with the note.
+func (t *Track) moveNotes(move func(pair *notePair) (start, end int64, ok bool)) {
+	// The shift of each note start, by channel and tick, for the events that move with the notes
+	shifts := make(map[[2]uint32]int64)
+	for _, pair := range notePairs(t.Events) {
+		start, end, ok := move(pair)
+		if !ok {
+			continue
+		}
+		if start < 0 {
+			start = 0
+		}
+		if end <= start {
+			// Keep the "note off" event after the "note on" event when the events are sorted
+			end = start + 1
+		}
+		position := [2]uint32{uint32(pair.on.Channel), pair.on.Tick}
+		if _, ok := shifts[position]; !ok {
+			shifts[position] = start - int64(pair.on.Tick)
+		}
+		pair.on.Tick = uint32(start)
+		if pair.off != nil {
+			pair.off.Tick = uint32(end)
+		}
+	}
+
+	for _, e := range t.Events {
+		if !isChannelMessage(e.Type) || e.Type == NoteOn || e.Type == NoteOff {
+			continue
+		}
+		if shift, ok := shifts[[2]uint32{uint32(e.Channel), e.Tick}]; ok {
+			e.Tick = uint32(math.Max(0, float64(int64(e.Tick)+shift)))
+		}
+	}
+
+	t.SortEvents()
+}
+
+// swingGridPosition returns the grid position that is closest to a tick, where
+// every second grid position is moved by the swing, in percent of two grid lengths
+func swingGridPosition(tick, grid int64, swing float64) int64 {
+	pair := tick / (2 * grid) * 2 * grid
+	offbeat := pair + int64(math.Round(2*float64(grid)*swing/100))
+	best := pair
+	for _, position := range []int64{offbeat, pair + 2*grid} {
+		if abs64(position-tick) < abs64(best-tick) {
+			best = position
+		}
+	}
+	return best
+}
+
+// abs64 returns the absolute value of an int64
+func abs64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,104 @@
+package midi
+
+import (
+	"testing"
+	"time"
+)
+
+// noteTrack returns a track with notes on channel 1, from pairs of start and end ticks
+func noteTrack(t *testing.T, ticks ...uint32) *Track {
+	track := NewTrack()
+	for i := 0; i+1 < len(ticks); i += 2 {
+		on, err := NewNoteOn(1, uint8(60+i), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		off, err := NewNoteOff(1, uint8(60+i), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		track.AddEventAt(ticks[i], on)
+		track.AddEventAt(ticks[i+1], off)
+	}
+	return track
+}
+
+// checkNotes checks the start and end ticks of the notes in a track
+func checkNotes(t *testing.T, track *Track, want ...uint32) {
+	t.Helper()
+	notes := track.Notes()
+	if len(notes) != len(want)/2 {
+		t.Fatalf("got %d notes, want %d", len(notes), len(want)/2)
+	}
+	for i, note := range notes {
+		if note.Tick != want[2*i] || note.Tick+note.DurationTicks != want[2*i+1] {
+			t.Errorf("note %d is from %d to %d, want from %d to %d",
+				i, note.Tick, note.Tick+note.DurationTicks, want[2*i], want[2*i+1])
+		}
+	}
+}
+
+func TestGridTicks(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	if ticks := m.GridTicks(16, false); ticks != 120 {
+		t.Errorf("sixteenth notes = %d ticks, want 120", ticks)
+	}
+	if ticks := m.GridTicks(8, true); ticks != 160 {
+		t.Errorf("eighth note triplets = %d ticks, want 160", ticks)
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	track := noteTrack(t, 5, 50, 110, 150, 230, 300)
+	track.Quantize(120, 1, 50)
+	checkNotes(t, track, 0, 45, 120, 160, 240, 310)
+
+	// Delta times are updated
+	var tick uint32
+	for _, e := range track.Events {
+		tick += e.DeltaTime
+		if tick != e.Tick {
+			t.Fatalf("the delta times do not match the ticks")
+		}
+	}
+}
+
+func TestQuantizeStrengthAndSwing(t *testing.T) {
+	track := noteTrack(t, 10, 60, 130, 200)
+	track.Quantize(120, 0.5, 50)
+	checkNotes(t, track, 5, 55, 125, 195)
+
+	// With 60% swing, the second sixteenth note of each pair is at 144
+	track = noteTrack(t, 130, 200, 250, 300)
+	track.Quantize(120, 1, 60)
+	checkNotes(t, track, 144, 214, 240, 290)
+}
+
+func TestQuantizeWithOptions(t *testing.T) {
+	track := noteTrack(t, 10, 100, 60, 130)
+	track.QuantizeWith(120, 1, 50, QuantizeOptions{Lengths: true, Window: 0.25})
+	// The second note is too far from the grid to be moved
+	checkNotes(t, track, 0, 120, 60, 130)
+}
+
+func TestQuantizeMovesEventsWithNotes(t *testing.T) {
+	m := NewMIDI(1, 96, 120)
+	m.PitchBendRange = 2
+	track := NewTrack()
+	if err := m.AddNote(track, &Note{Frequency: 445, Duration: time.Second / 2, Velocity: 100, Channel: 1, Program: 3}); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range track.Events {
+		e.Tick += 4
+	}
+	track.Quantize(24, 1, 50)
+	for _, e := range track.Events {
+		if e.Tick == 4 {
+			t.Errorf("event %X was not moved with the note", e.Type)
+		}
+	}
+	// The pitch bend and the program change still come before the note
+	if track.Events[len(track.Events)-2].Type != NoteOn {
+		t.Errorf("the note should come after the events at its start")
+	}
+}
@@ -0,0 +1,234 @@
+package midi
+
+import (
+	"container/heap"
+	"iter"
+)
+
+// Filter selects events
+type Filter func(e *Event) bool
+
+// OfType selects events of the given types, like NoteOn or EventMeta
+func OfType(types ...uint8) Filter {
+	return func(e *Event) bool {
+		for _, t := range types {
+			if e.Type == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// OnChannel selects channel events on the given channels, from 1 to 16
+func OnChannel(channels ...uint8) Filter {
+	return func(e *Event) bool {
+		if !isChannelMessage(e.Type) {
+			return false
+		}
+		for _, channel := range channels {
+			if e.Channel == channel {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// InTickRange selects events from the start tick, until before the end tick
+func InTickRange(start, end uint32) Filter {
+	return func(e *Event) bool {
+		return e.Tick >= start && e.Tick < end
+	}
+}
+
+// InNoteRange selects "note on", "note off" and key pressure events for the keys from low to high
+func InNoteRange(low, high uint8) Filter {
+	return func(e *Event) bool {
+		switch e.Type {
+		case NoteOn, NoteOff, PolyphonicKeyPressure:
+			return len(e.Data) > 0 && e.Data[0] >= low && e.Data[0] <= high
+		}
+		return false
+	}
+}
+
+// Not selects the events that the filter does not select
+func Not(filter Filter) Filter {
+	return func(e *Event) bool {
+		return !filter(e)
+	}
+}
+
+// Or selects the events that any of the filters select
+func Or(filters ...Filter) Filter {
+	return func(e *Event) bool {
+		for _, filter := range filters {
+			if filter(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// And selects the events that all of the filters select
+func And(filters ...Filter) Filter {
+	return func(e *Event) bool {
+		for _, filter := range filters {
+			if !filter(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Select returns the events in a track that all of the filters select, in the order of the track
+func (t *Track) Select(filters ...Filter) []*Event {
+	match := And(filters...)
+	var selected []*Event
+	for _, e := range t.Events {
+		if match(e) {
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
+
+// Delete removes the events that all of the filters select from a track, and
+// returns the number of removed events
+func (t *Track) Delete(filters ...Filter) int {
+	match := And(filters...)
+	kept := t.Events[:0]
+	for _, e := range t.Events {
+		if !match(e) {
+			kept = append(kept, e)
+		}
+	}
+	removed := len(t.Events) - len(kept)
+	// Clear the removed events at the end, so that they can be garbage collected
+	for i := len(kept); i < len(t.Events); i++ {
+		t.Events[i] = nil
+	}
+	t.Events = kept
+	t.SortEvents()
+	return removed
+}
+
+// Move moves the events that all of the filters select by a number of ticks, which
+// may be negative, and returns the number of moved events. Events are not moved
+// to before the start of the track.
+func (t *Track) Move(ticks int64, filters ...Filter) int {
+	moved := 0
+	for _, e := range t.Select(filters...) {
+		e.Tick = shiftTick(e.Tick, ticks)
+		moved++
+	}
+	t.SortEvents()
+	return moved
+}
+
+// CopyTo adds copies of the events that all of the filters select to another track,
+// moved by a number of ticks, and returns the number of copied events
+func (t *Track) CopyTo(dst *Track, ticks int64, filters ...Filter) int {
+	selected := t.Select(filters...)
+	for _, e := range selected {
+		c := *e
+		c.Data = append([]byte(nil), e.Data...)
+		c.Tick = shiftTick(e.Tick, ticks)
+		dst.Events = append(dst.Events, &c)
+	}
+	dst.SortEvents()
+	return len(selected)
+}
+
+// shiftTick adds a number of ticks to a tick, and keeps the result from 0 to the highest tick
+func shiftTick(tick uint32, ticks int64) uint32 {
+	shifted := int64(tick) + ticks
+	switch {
+	case shifted < 0:
+		return 0
+	case shifted > int64(^uint32(0)):
+		return ^uint32(0)
+	}
+	return uint32(shifted)
+}
+
+// All returns an iterator over the events in a track, in time order, that all of the filters select
+func (t *Track) All(filters ...Filter) iter.Seq[*Event] {
+	return mergedEvents([]*Track{t}, filters)
+}
+
+// All returns an iterator over the events in all the tracks, in time order, that
+// all of the filters select. Events at the same tick are in the order of the
+// tracks, except that "note off" events come first.
+func (m *MIDI) All(filters ...Filter) iter.Seq[*Event] {
+	return mergedEvents(m.Tracks, filters)
+}
+
+// trackCursor is the next event in a sorted track, for merging tracks
+type trackCursor struct {
+	events []*Event
+	index  int
+	track  int
+}
+
+// eventHeap is a heap of track cursors, with the earliest event first
+type eventHeap []*trackCursor
+
+func (h eventHeap) Len() int {
+	return len(h)
+}
+
+func (h eventHeap) Less(i, j int) bool {
+	a, b := h[i].events[h[i].index], h[j].events[h[j].index]
+	if a.Tick != b.Tick {
+		return a.Tick < b.Tick
+	}
+	if a.IsNoteOff() != b.IsNoteOff() {
+		return a.IsNoteOff()
+	}
+	return h[i].track < h[j].track
+}
+
+func (h eventHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *eventHeap) Push(x any) {
+	*h = append(*h, x.(*trackCursor))
+}
+
+func (h *eventHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// mergedEvents returns an iterator that merges the sorted events of tracks with a heap
+func mergedEvents(tracks []*Track, filters []Filter) iter.Seq[*Event] {
+	match := And(filters...)
+	return func(yield func(*Event) bool) {
+		h := make(eventHeap, 0, len(tracks))
+		for i, t := range tracks {
+			if events := sortedEvents(t.Events); len(events) > 0 {
+				h = append(h, &trackCursor{events: events, track: i})
+			}
+		}
+		heap.Init(&h)
+		for h.Len() > 0 {
+			c := h[0]
+			e := c.events[c.index]
+			if c.index++; c.index < len(c.events) {
+				heap.Fix(&h, 0)
+			} else {
+				heap.Pop(&h)
+			}
+			if match(e) && !yield(e) {
+				return
+			}
+		}
+	}
+}
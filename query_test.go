@@ -0,0 +1,109 @@
+package midi
+
+import (
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	track := noteTrack(t, 0, 96, 96, 192, 192, 288)
+	pc, err := NewProgramChange(2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	track.AddEventAt(100, pc)
+	track.AddEventAt(0, NewTrackName("Melody"))
+
+	if n := len(track.Select(OfType(NoteOn))); n != 3 {
+		t.Errorf("got %d note on events, want 3", n)
+	}
+	if n := len(track.Select(OnChannel(2))); n != 1 {
+		t.Errorf("got %d events on channel 2, want 1", n)
+	}
+	if n := len(track.Select(InTickRange(96, 192), OfType(NoteOn, NoteOff))); n != 2 {
+		t.Errorf("got %d note events from tick 96 to 191, want 2", n)
+	}
+	if n := len(track.Select(InNoteRange(62, 64))); n != 4 {
+		t.Errorf("got %d events for keys 62 to 64, want 4", n)
+	}
+	if n := len(track.Select(Or(OnChannel(2), OfType(EventMeta)))); n != 2 {
+		t.Errorf("got %d events on channel 2 or meta events, want 2", n)
+	}
+	if n := len(track.Select(Not(OnChannel(1)))); n != 2 {
+		t.Errorf("got %d events that are not on channel 1, want 2", n)
+	}
+	if n := len(track.Select(func(e *Event) bool { return e.Tick > 150 })); n != 3 {
+		t.Errorf("got %d events after tick 150, want 3", n)
+	}
+}
+
+func TestDeleteMoveCopy(t *testing.T) {
+	track := noteTrack(t, 0, 96, 96, 192)
+	if n := track.Delete(InNoteRange(60, 60)); n != 2 {
+		t.Errorf("deleted %d events, want 2", n)
+	}
+	if len(track.Events) != 2 || track.Events[0].Tick != 96 || track.Events[0].DeltaTime != 96 {
+		t.Fatalf("unexpected events after deleting: %+v", track.Events)
+	}
+
+	if n := track.Move(-200, OfType(NoteOn)); n != 1 {
+		t.Errorf("moved %d events, want 1", n)
+	}
+	if track.Events[0].Tick != 0 || track.Events[1].DeltaTime != 192 {
+		t.Errorf("the note should start at 0, and end at 192")
+	}
+
+	dst := noteTrack(t, 0, 48)
+	if n := track.CopyTo(dst, 96); n != 2 {
+		t.Errorf("copied %d events, want 2", n)
+	}
+	checkNotes(t, dst, 0, 48, 96, 288)
+	dst.Events[2].Data[0] = 70
+	if track.Events[0].Data[0] == 70 {
+		t.Errorf("the copied events should not share data with the original events")
+	}
+}
+
+func TestAll(t *testing.T) {
+	m := NewMIDI(1, 96, 120)
+	a := noteTrack(t, 96, 192, 0, 96)
+	b := noteTrack(t, 48, 96, 96, 144)
+	m.AddTrack(a)
+	m.AddTrack(b)
+
+	var ticks []uint32
+	var previous *Event
+	for e := range m.All() {
+		if previous != nil && previous.Tick == e.Tick && !previous.IsNoteOff() && e.IsNoteOff() {
+			t.Errorf("a note off event at tick %d comes after a note on event", e.Tick)
+		}
+		ticks = append(ticks, e.Tick)
+		previous = e
+	}
+	want := []uint32{0, 48, 96, 96, 96, 96, 144, 192}
+	if len(ticks) != len(want) {
+		t.Fatalf("got ticks %v, want %v", ticks, want)
+	}
+	for i := range want {
+		if ticks[i] != want[i] {
+			t.Fatalf("got ticks %v, want %v", ticks, want)
+		}
+	}
+
+	// The iteration can stop early, and filters are applied
+	count := 0
+	for range m.All(OfType(NoteOn)) {
+		if count++; count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("got %d events, want 2", count)
+	}
+	n := 0
+	for range a.All(OfType(NoteOff)) {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("got %d note off events in the track, want 2", n)
+	}
+}
@@ -0,0 +1,149 @@
+//go:build linux
+
+package midi
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// rawMIDIDevices is the pattern of the ALSA raw MIDI device files
+const rawMIDIDevices = "/dev/snd/midiC*D*"
+
+// RawMIDIPort is an ALSA raw MIDI device, like /dev/snd/midiC1D0
+type RawMIDIPort struct {
+	Path   string // The path of the device file
+	Card   int    // The sound card number
+	Device int    // The device number on the sound card
+	Name   string // The name of the device, or the path if it is unknown
+}
+
+// RawMIDIPorts returns the ALSA raw MIDI devices, sorted by card and device number
+func RawMIDIPorts() ([]RawMIDIPort, error) {
+	paths, err := filepath.Glob(rawMIDIDevices)
+	if err != nil {
+		return nil, err
+	}
+	var ports []RawMIDIPort
+	for _, path := range paths {
+		port, err := parseRawMIDIPath(path)
+		if err != nil {
+			continue
+		}
+		port.Name = rawMIDIName(port.Card, port.Device)
+		if port.Name == "" {
+			port.Name = path
+		}
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Card != ports[j].Card {
+			return ports[i].Card < ports[j].Card
+		}
+		return ports[i].Device < ports[j].Device
+	})
+	return ports, nil
+}
+
+// parseRawMIDIPath returns the card and device number of a raw MIDI device file
+func parseRawMIDIPath(path string) (RawMIDIPort, error) {
+	port := RawMIDIPort{Path: path}
+	if _, err := fmt.Sscanf(filepath.Base(path), "midiC%dD%d", &port.Card, &port.Device); err != nil {
+		return port, fmt.Errorf("not a raw MIDI device: %s", path)
+	}
+	return port, nil
+}
+
+// rawMIDIName returns the name of a raw MIDI device from /proc/asound, or an empty string
+func rawMIDIName(card, device int) string {
+	f, err := os.Open(fmt.Sprintf("/proc/asound/card%d/midi%d", card, device))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return ""
+}
+
+// RawMIDIOutput sends events to a raw MIDI device
+type RawMIDIOutput struct {
+	w   io.WriteCloser
+	buf bytes.Buffer
+	enc *Encoder
+}
+
+// OpenRawMIDIOutput opens a raw MIDI device for sending events, like /dev/snd/midiC1D0
+func OpenRawMIDIOutput(path string) (*RawMIDIOutput, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewRawMIDIOutput(f), nil
+}
+
+// NewRawMIDIOutput creates a RawMIDIOutput that writes raw MIDI bytes to a device
+// that is already open, or to a stand-in for a device, like a pipe
+func NewRawMIDIOutput(w io.WriteCloser) *RawMIDIOutput {
+	o := &RawMIDIOutput{w: w}
+	o.enc = NewEncoder(&o.buf)
+	return o
+}
+
+// Send sends an event to the device. Meta events are skipped.
+func (o *RawMIDIOutput) Send(e *Event) error {
+	o.buf.Reset()
+	if err := o.enc.Encode(e); err != nil {
+		return err
+	}
+	if o.buf.Len() == 0 {
+		return nil
+	}
+	// The whole message is written at once, so that it is not split up
+	_, err := o.w.Write(o.buf.Bytes())
+	return err
+}
+
+// Close closes the device
+func (o *RawMIDIOutput) Close() error {
+	return o.w.Close()
+}
+
+// RawMIDIInput receives events from a raw MIDI device
+type RawMIDIInput struct {
+	r   io.ReadCloser
+	dec *Decoder
+}
+
+// OpenRawMIDIInput opens a raw MIDI device for receiving events, like /dev/snd/midiC1D0
+func OpenRawMIDIInput(path string) (*RawMIDIInput, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRawMIDIInput(f), nil
+}
+
+// NewRawMIDIInput creates a RawMIDIInput that reads raw MIDI bytes from a device
+// that is already open, or from a stand-in for a device, like a pipe
+func NewRawMIDIInput(r io.ReadCloser) *RawMIDIInput {
+	return &RawMIDIInput{r: r, dec: NewDecoder(r)}
+}
+
+// Receive waits for the next event from the device
+func (i *RawMIDIInput) Receive() (*Event, error) {
+	return i.dec.Decode()
+}
+
+// Close closes the device
+func (i *RawMIDIInput) Close() error {
+	return i.r.Close()
+}
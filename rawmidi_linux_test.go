@@ -0,0 +1,67 @@
+//go:build linux
+
+package midi
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseRawMIDIPath(t *testing.T) {
+	port, err := parseRawMIDIPath("/dev/snd/midiC2D1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port.Card != 2 || port.Device != 1 {
+		t.Errorf("card and device = %d and %d, want 2 and 1", port.Card, port.Device)
+	}
+	if _, err := parseRawMIDIPath("/dev/snd/pcmC0D0p"); err == nil {
+		t.Errorf("expected an error for a device that is not a raw MIDI device")
+	}
+}
+
+func TestRawMIDIPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := NewRawMIDIOutput(w)
+	in := NewRawMIDIInput(r)
+	defer in.Close()
+
+	m := NewMIDI(0, 96, 120)
+	track := NewTrack()
+	if err := m.AddNote(track, &Note{Frequency: 440, Duration: time.Second / 2, Velocity: 100, Channel: 2, Program: 5}); err != nil {
+		t.Fatal(err)
+	}
+	sysex, err := NewSysEx([]byte{0x7E, 0x7F, 0x09, 0x01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := append([]*Event{sysex, NewTrackName("Meta events are skipped")}, track.Events...)
+	for _, e := range events {
+		if err := out.Send(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := append([]*Event{sysex}, track.Events...)
+	for _, e := range want {
+		got, err := in.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Type != e.Type || got.Channel != e.Channel || !bytes.Equal(got.Data, e.Data) {
+			t.Errorf("received %X on channel %d with data %X, want %X on channel %d with data %X",
+				got.Type, got.Channel, got.Data, e.Type, e.Channel, e.Data)
+		}
+	}
+	if e, err := in.Receive(); err == nil {
+		t.Errorf("expected the end of the stream, got %+v", e)
+	}
+}
@@ -0,0 +1,231 @@
+package midi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ReadMIDI reads a Standard MIDI File from an io.Reader
+func ReadMIDI(r io.Reader) (*MIDI, error) {
+	return Read(r)
+}
+
+// Read parses a Standard MIDI File from an io.Reader into a MIDI struct.
+// Channels of the returned events are numbered from 1, like DefaultNoteChannel.
+func Read(r io.Reader) (*MIDI, error) {
+	m, numTracks, err := readMIDIHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read chunks until all the tracks have been found
+	for len(m.Tracks) < int(numTracks) {
+		chunkType, chunkData, err := readChunk(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("expected %d tracks, but found %d", numTracks, len(m.Tracks))
+			}
+			return nil, err
+		}
+		// Alien chunks must be ignored, according to the specification
+		if chunkType != "MTrk" {
+			continue
+		}
+		track, err := readTrack(chunkData, m)
+		if err != nil {
+			return nil, fmt.Errorf("track %d: %w", len(m.Tracks)+1, err)
+		}
+		m.AddTrack(track)
+	}
+
+	// A Set Tempo event at the start gives the BPM of the song. Until the first
+	// Set Tempo event, the tempo is DefaultBPM.
+	for _, track := range m.Tracks {
+		if bpm, ok := startTempo(track); ok {
+			m.BPM = bpm
+			break
+		}
+	}
+
+	return m, nil
+}
+
+// startTempo returns the BPM of a Set Tempo event at tick 0 in a track
+func startTempo(t *Track) (float64, bool) {
+	for _, e := range t.Events {
+		if e.Tick > 0 {
+			break
+		}
+		if bpm, ok := e.Tempo(); ok {
+			return bpm, true
+		}
+	}
+	return 0, false
+}
+
+func readMIDIHeader(r io.Reader) (*MIDI, uint16, error) {
+	chunkType, chunkData, err := readChunk(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if chunkType != "MThd" {
+		return nil, 0, fmt.Errorf("invalid MIDI header: %q", chunkType)
+	}
+	if len(chunkData) < 6 {
+		return nil, 0, fmt.Errorf("MIDI header is too short: %d bytes", len(chunkData))
+	}
+
+	// Any extra header bytes are ignored, they may be used by future versions of the format
+	buf := bytes.NewReader(chunkData)
+	format, _ := readMIDIUint16(buf)
+	numTracks, _ := readMIDIUint16(buf)
+	division, _ := readMIDIUint16(buf)
+
+	return NewMIDI(format, division, DefaultBPM), numTracks, nil
+}
+
+// readChunk reads the type and the data of a chunk
+func readChunk(r io.Reader) (string, []byte, error) {
+	var chunkType [4]byte
+	if _, err := io.ReadFull(r, chunkType[:]); err != nil {
+		return "", nil, err
+	}
+	length, err := readMIDIUint32(r)
+	if err != nil {
+		return "", nil, unexpectedEOF(err)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, int64(length)))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(data) != int(length) {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+	return string(chunkType[:]), data, nil
+}
+
+func readTrack(data []byte, m *MIDI) (*Track, error) {
+	t := NewTrack()
+	r := bytes.NewReader(data)
+
+	// The current running status, 0 if there is none
+	var status uint8
+
+	// The absolute position of the current event
+	var tick uint32
+
+	// The current program for each channel in this track
+	programs := make(map[uint8]uint8)
+
+	for r.Len() > 0 {
+		deltaTime, err := readVariableLengthQuantity(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		b, err := readMIDIUint8(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		tick += deltaTime
+		e := &Event{Tick: tick, DeltaTime: deltaTime}
+
+		switch {
+		case b == EventMeta:
+			// Meta and sysex events cancel any running status
+			status = 0
+			if e.Meta, err = readMIDIUint8(r); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if e.Data, err = readLengthPrefixed(r); err != nil {
+				return nil, err
+			}
+			e.Type = EventMeta
+		case b == EventSysEx || b == EventSysExEscape:
+			status = 0
+			if e.Data, err = readLengthPrefixed(r); err != nil {
+				return nil, err
+			}
+			e.Type = b
+		case b >= 0xF0:
+			return nil, fmt.Errorf("unexpected system message %X in track data", b)
+		default:
+			var first uint8
+			if b&0x80 != 0 {
+				status = b
+				if first, err = readMIDIUint8(r); err != nil {
+					return nil, unexpectedEOF(err)
+				}
+			} else if status == 0 {
+				return nil, fmt.Errorf("data byte %X without running status", b)
+			} else {
+				// Running status, this is the first data byte
+				first = b
+			}
+			e.Type = status & 0xF0
+			e.Channel = status&0x0F + 1
+			e.Data = []byte{first}
+			if channelMessageLength(e.Type) == 2 {
+				second, err := readMIDIUint8(r)
+				if err != nil {
+					return nil, unexpectedEOF(err)
+				}
+				e.Data = append(e.Data, second)
+			}
+			for _, d := range e.Data {
+				if d&0x80 != 0 {
+					return nil, fmt.Errorf("invalid data byte %X", d)
+				}
+			}
+			if e.Type == EventProgramChange {
+				programs[e.Channel] = first
+				m.SetProgram(e.Channel, first)
+			}
+			e.Program = programs[e.Channel]
+		}
+
+		t.AddEvent(e)
+
+		// Anything after the End of Track event is ignored
+		if e.IsMeta(MetaEndOfTrack) {
+			break
+		}
+	}
+
+	return t, nil
+}
+
+// readLengthPrefixed reads a variable-length quantity, followed by that number of bytes
+func readLengthPrefixed(r *bytes.Reader) ([]byte, error) {
+	length, err := readVariableLengthQuantity(r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if int64(length) > int64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return data, nil
+}
+
+// channelMessageLength returns the number of data bytes for a channel message type
+func channelMessageLength(eventType uint8) int {
+	switch eventType {
+	case ProgramChange, ChannelPressure:
+		return 1
+	}
+	return 2
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
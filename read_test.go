@@ -0,0 +1,167 @@
+package midi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// testFile is a format 0 file with a tempo event, a sysex event, a program change
+// and a note, where the note off event uses running status.
+var testFile = []byte{
+	'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0x01, 0xE0,
+	'M', 'T', 'r', 'k', 0, 0, 0, 28,
+	0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20, // Set Tempo, 500000 microseconds per quarter note
+	0x00, 0xF0, 0x03, 0x7E, 0x7F, 0xF7, // Sysex
+	0x00, 0xC2, 0x05, // Program change on channel 3
+	0x00, 0x92, 0x3C, 0x40, // Note on, channel 3
+	0x83, 0x60, 0x3C, 0x00, // Running status, velocity 0, 480 ticks later
+	0x00, 0xFF, 0x2F, 0x00, // End of Track
+}
+
+func TestRead(t *testing.T) {
+	m, err := Read(bytes.NewReader(testFile))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if m.Format != 0 || m.Division != 480 || len(m.Tracks) != 1 {
+		t.Fatalf("Read header = format %d, division %d, %d tracks, want 0, 480, 1", m.Format, m.Division, len(m.Tracks))
+	}
+	if m.BPM != 120 {
+		t.Errorf("Read BPM = %f, want 120", m.BPM)
+	}
+
+	events := m.Tracks[0].Events
+	if len(events) != 6 {
+		t.Fatalf("Read %d events, want 6", len(events))
+	}
+	if events[0].Type != EventMeta || events[0].Meta != 0x51 || len(events[0].Data) != 3 {
+		t.Errorf("First event is not a Set Tempo meta event: %+v", events[0])
+	}
+	if events[1].Type != EventSysEx || !bytes.Equal(events[1].Data, []byte{0x7E, 0x7F, 0xF7}) {
+		t.Errorf("Second event is not a sysex event: %+v", events[1])
+	}
+	if events[2].Type != EventProgramChange || events[2].Channel != 3 || events[2].Data[0] != 5 {
+		t.Errorf("Third event is not a program change on channel 3: %+v", events[2])
+	}
+	noteOff := events[4]
+	if noteOff.Type != EventNoteOn || noteOff.Channel != 3 || noteOff.DeltaTime != 480 || noteOff.Program != 5 {
+		t.Errorf("Running status event = %+v, want a note on for channel 3, 480 ticks later", noteOff)
+	}
+	if !bytes.Equal(noteOff.Data, []byte{0x3C, 0x00}) {
+		t.Errorf("Running status event data = %X, want 3C 00", noteOff.Data)
+	}
+	if m.GetProgram(3) != 5 {
+		t.Errorf("GetProgram(3) = %d, want 5", m.GetProgram(3))
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	if _, err := Read(bytes.NewReader([]byte("RIFF\x00\x00\x00\x06"))); err == nil {
+		t.Error("Read should fail for a file without an MThd header")
+	}
+	if _, err := Read(bytes.NewReader(testFile[:len(testFile)-6])); err == nil {
+		t.Error("Read should fail for a truncated file")
+	}
+	noStatus := append([]byte{}, testFile[:22]...)
+	noStatus = append(noStatus, 0x00, 0x3C, 0x40)
+	noStatus[21] = 3
+	if _, err := Read(bytes.NewReader(noStatus)); err == nil {
+		t.Error("Read should fail for a data byte without running status")
+	}
+	longDelta := append([]byte{}, testFile[:22]...)
+	longDelta = append(longDelta, 0x81, 0x80, 0x80, 0x80, 0x00, 0xC2, 0x05)
+	longDelta[21] = 7
+	if _, err := Read(bytes.NewReader(longDelta)); err == nil {
+		t.Error("Read should fail for a delta time that is longer than 4 bytes")
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	m, err := Read(bytes.NewReader(testFile))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := m.Write(buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	m2, err := Read(buf)
+	if err != nil {
+		t.Fatalf("Read of the written file failed: %v", err)
+	}
+	if len(m2.Tracks) != 1 || len(m2.Tracks[0].Events) != len(m.Tracks[0].Events) {
+		t.Fatalf("Read back %d tracks, want 1 track with %d events", len(m2.Tracks), len(m.Tracks[0].Events))
+	}
+	for i, e := range m2.Tracks[0].Events {
+		want := m.Tracks[0].Events[i]
+		if e.Type != want.Type || e.Channel != want.Channel || e.Meta != want.Meta || e.DeltaTime != want.DeltaTime || !bytes.Equal(e.Data, want.Data) {
+			t.Errorf("Event %d = %+v, want %+v", i, e, want)
+		}
+	}
+}
+
+func TestReadDelayedTempo(t *testing.T) {
+	data := []byte{
+		'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0x01, 0xE0,
+		'M', 'T', 'r', 'k', 0, 0, 0, 12,
+		0x87, 0x40, 0xFF, 0x51, 0x03, 0x0F, 0x42, 0x40, // Set Tempo at tick 960, 60 BPM
+		0x00, 0xFF, 0x2F, 0x00, // End of Track
+	}
+	m, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if m.BPM != DefaultBPM {
+		t.Errorf("Read BPM = %f, want %d until the first Set Tempo event", m.BPM, DefaultBPM)
+	}
+	tempoMap := m.TempoMap()
+	if d := tempoMap.TicksToDuration(960); d != time.Second {
+		t.Errorf("Tick 960 is at %v, want 1s", d)
+	}
+	if d := tempoMap.TicksToDuration(1440); d != 2*time.Second {
+		t.Errorf("Tick 1440 is at %v, want 2s", d)
+	}
+}
+
+func TestMetaTypeFF(t *testing.T) {
+	data := []byte{
+		'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0x01, 0xE0,
+		'M', 'T', 'r', 'k', 0, 0, 0, 8,
+		0x00, 0xFF, 0xFF, 0x00, // Meta event of type FF, which is not a System Reset
+		0x00, 0xFF, 0x2F, 0x00, // End of Track
+	}
+	m, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	e := m.Tracks[0].Events[0]
+	if e.IsSystemReset() || !e.IsMeta(0xFF) {
+		t.Fatalf("Read %+v, want a meta event of type FF", e)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := m.Write(buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	m2, err := Read(buf)
+	if err != nil {
+		t.Fatalf("Read of the written file failed: %v", err)
+	}
+	found := false
+	for _, e := range m2.Tracks[0].Events {
+		found = found || e.IsMeta(0xFF)
+	}
+	if !found {
+		t.Error("The meta event of type FF was not written")
+	}
+
+	// Meta events are not sent to devices
+	live := new(bytes.Buffer)
+	if err := NewEncoder(live).Encode(e); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if live.Len() != 0 {
+		t.Errorf("Encoded the meta event as %X for a device, want nothing", live.Bytes())
+	}
+}
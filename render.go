@@ -0,0 +1,116 @@
+package midi
+
+import (
+	"math"
+)
+
+// sound is a note that is playing in a software synthesizer
+type sound interface {
+	next(bend float64) float64 // returns the next sample, with the pitch bent by the given semitones
+	release()                  // starts the release of the note
+	done() bool                // checks if the note is released and silent
+}
+
+// playingSound is a sound with the channel and key that started it
+type playingSound struct {
+	sound
+	channel  uint8
+	key      uint8
+	released bool
+}
+
+// startSound returns the sounds of a new note on a channel. The frequency is the
+// frequency of the key, and the bank is 128 for the drum channel.
+type startSound func(channel, key, velocity uint8, frequency float64, program uint8, bank uint16) []sound
+
+// renderEvents renders the events in all the tracks of a MIDI song to mono samples.
+// The keys are tuned with m.Tuning if it is set without a pitch bend range, and pitch
+// bend events bend the notes by up to m.PitchBendRange semitones, or 2 semitones.
+func renderEvents(m *MIDI, sampleRate int, start startSound) []float64 {
+	tempoMap := m.TempoMap()
+	bendRange := m.PitchBendRange
+	if bendRange <= 0 {
+		bendRange = 2
+	}
+	var tuning Tuning = EqualTemperament{A4: 440}
+	if m.Tuning != nil && m.PitchBendRange <= 0 {
+		tuning = m.Tuning
+	}
+
+	var (
+		samples  []float64
+		sounds   []*playingSound
+		programs = make(map[uint8]uint8)
+		banks    = make(map[uint8]uint16)
+		bends    = make(map[uint8]float64) // the pitch bend of each channel, in semitones
+	)
+	for e := range m.All() {
+		until := int(math.Round(tempoMap.TicksToDuration(e.Tick).Seconds() * float64(sampleRate)))
+		samples = renderSounds(samples, sounds, bends, until)
+		sounds = playingSounds(sounds)
+		switch {
+		case e.IsNoteOff():
+			for _, s := range sounds {
+				if s.channel == e.Channel && s.key == e.Data[0] && !s.released {
+					s.release()
+					s.released = true
+					break
+				}
+			}
+		case e.Type == NoteOn:
+			key := e.Data[0]
+			bank := banks[e.Channel]
+			if e.Channel == DrumChannel {
+				bank = 128
+			}
+			for _, s := range start(e.Channel, key, e.Data[1], tuning.Frequency(int(key)), programs[e.Channel], bank) {
+				sounds = append(sounds, &playingSound{sound: s, channel: e.Channel, key: key})
+			}
+		case e.Type == ProgramChange:
+			programs[e.Channel] = e.Data[0]
+		case e.Type == ControlChange && e.Data[0] == 0:
+			// Bank select
+			banks[e.Channel] = uint16(e.Data[1])
+		case e.Type == PitchBend:
+			value, _ := e.PitchBendValue()
+			bends[e.Channel] = float64(value) / 8192 * bendRange
+		}
+	}
+
+	// Release the notes that are not released, and render until they are silent
+	for _, s := range sounds {
+		s.release()
+	}
+	for len(sounds) > 0 {
+		samples = renderSounds(samples, sounds, bends, len(samples)+sampleRate/10)
+		sounds = playingSounds(sounds)
+	}
+	return samples
+}
+
+// renderSounds renders sounds until the given sample, with the pitch bend of each channel in semitones
+func renderSounds(samples []float64, sounds []*playingSound, bends map[uint8]float64, until int) []float64 {
+	start := len(samples)
+	if until <= start {
+		return samples
+	}
+	samples = append(samples, make([]float64, until-start)...)
+	for _, s := range sounds {
+		bend := bends[s.channel]
+		for i := start; i < until && !s.done(); i++ {
+			samples[i] += s.next(bend)
+		}
+	}
+	return samples
+}
+
+// playingSounds returns the sounds that are not done
+func playingSounds(sounds []*playingSound) []*playingSound {
+	playing := sounds[:0]
+	for _, s := range sounds {
+		if !s.done() {
+			playing = append(playing, s)
+		}
+	}
+	return playing
+}
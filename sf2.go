@@ -0,0 +1,650 @@
+package midi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// SoundFont is a SoundFont 2 (.sf2) file, with presets, instruments and samples
+type SoundFont struct {
+	Version     [2]uint16 // The major and minor version of the SoundFont format
+	Name        string
+	Presets     []*SoundFontPreset
+	Instruments []*SoundFontInstrument
+	Samples     []*SoundFontSample
+	SampleData  []int16 // The 16-bit samples of all the samples
+}
+
+// SoundFontPreset is a sound that can be selected with a bank and a program number
+type SoundFontPreset struct {
+	Name    string
+	Program uint16
+	Bank    uint16 // The bank number, which is 128 for percussion
+	Global  *SoundFontZone
+	Zones   []*SoundFontZone
+}
+
+// SoundFontInstrument is a set of samples for different keys and velocities
+type SoundFontInstrument struct {
+	Name   string
+	Global *SoundFontZone
+	Zones  []*SoundFontZone
+}
+
+// SoundFontZone is a part of a preset or an instrument, with generators that give
+// the key and velocity range and the sound. Preset zones use an instrument, and
+// instrument zones use a sample. Global zones use neither.
+type SoundFontZone struct {
+	Generators []SoundFontGenerator
+	Modulators []SoundFontModulator
+	Instrument *SoundFontInstrument
+	Sample     *SoundFontSample
+}
+
+// SoundFontGenerator sets a parameter of a zone
+type SoundFontGenerator struct {
+	Operator uint16
+	Amount   int16
+}
+
+// SoundFontModulator connects a source, like the velocity, to a parameter of a zone
+type SoundFontModulator struct {
+	Source       uint16
+	Destination  uint16
+	Amount       int16
+	AmountSource uint16
+	Transform    uint16
+}
+
+// SoundFontSample is a sample in the sample data of a SoundFont
+type SoundFontSample struct {
+	Name            string
+	Start           uint32 // The first sample in the sample data
+	End             uint32 // The sample after the last sample
+	LoopStart       uint32 // The first sample of the loop
+	LoopEnd         uint32 // The sample after the last sample of the loop
+	SampleRate      uint32
+	OriginalPitch   uint8 // The MIDI key of the recorded pitch
+	PitchCorrection int8  // The pitch correction in cents
+	Link            uint16
+	Type            uint16
+}
+
+// SoundFont generator operators, used by the renderer
+const (
+	sfStartAddrsOffset           = 0
+	sfEndAddrsOffset             = 1
+	sfStartloopAddrsOffset       = 2
+	sfEndloopAddrsOffset         = 3
+	sfStartAddrsCoarseOffset     = 4
+	sfEndAddrsCoarseOffset       = 12
+	sfDelayVolEnv                = 33
+	sfAttackVolEnv               = 34
+	sfHoldVolEnv                 = 35
+	sfDecayVolEnv                = 36
+	sfSustainVolEnv              = 37
+	sfReleaseVolEnv              = 38
+	sfInstrument                 = 41
+	sfKeyRange                   = 43
+	sfVelRange                   = 44
+	sfStartloopAddrsCoarseOffset = 45
+	sfKeynum                     = 46
+	sfVelocity                   = 47
+	sfInitialAttenuation         = 48
+	sfEndloopAddrsCoarseOffset   = 50
+	sfCoarseTune                 = 51
+	sfFineTune                   = 52
+	sfSampleID                   = 53
+	sfSampleModes                = 54
+	sfScaleTuning                = 56
+	sfOverridingRootKey          = 58
+)
+
+// soundFontDefaults are the default values of the instrument generators that are not 0
+var soundFontDefaults = map[uint16]int16{
+	sfDelayVolEnv:       -12000,
+	sfAttackVolEnv:      -12000,
+	sfHoldVolEnv:        -12000,
+	sfDecayVolEnv:       -12000,
+	sfReleaseVolEnv:     -12000,
+	sfKeyRange:          127 << 8,
+	sfVelRange:          127 << 8,
+	sfKeynum:            -1,
+	sfVelocity:          -1,
+	sfScaleTuning:       100,
+	sfOverridingRootKey: -1,
+}
+
+// Generator returns the amount of a generator in the zone
+func (z *SoundFontZone) Generator(operator uint16) (int16, bool) {
+	for _, g := range z.Generators {
+		if g.Operator == operator {
+			return g.Amount, true
+		}
+	}
+	return 0, false
+}
+
+// riffChunk is a chunk in a RIFF file
+type riffChunk struct {
+	id   string
+	data []byte
+}
+
+// LoadSoundFont loads a SoundFont 2 (.sf2) file
+func LoadSoundFont(filename string) (*SoundFont, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSoundFont(f)
+}
+
+// ReadSoundFont reads a SoundFont 2 (.sf2) file
+func ReadSoundFont(r io.Reader) (*SoundFont, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := riffChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) != 1 || chunks[0].id != "RIFF" || !bytes.HasPrefix(chunks[0].data, []byte("sfbk")) {
+		return nil, fmt.Errorf("not a SoundFont 2 file")
+	}
+	lists, err := riffChunks(chunks[0].data[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	sf := &SoundFont{}
+	pdta := make(map[string][]byte)
+	for _, list := range lists {
+		if list.id != "LIST" || len(list.data) < 4 {
+			continue
+		}
+		subchunks, err := riffChunks(list.data[4:])
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range subchunks {
+			switch string(list.data[:4]) + "/" + c.id {
+			case "INFO/ifil":
+				if len(c.data) >= 4 {
+					sf.Version = [2]uint16{binary.LittleEndian.Uint16(c.data), binary.LittleEndian.Uint16(c.data[2:])}
+				}
+			case "INFO/INAM":
+				sf.Name = soundFontString(c.data)
+			case "sdta/smpl":
+				sf.SampleData = make([]int16, len(c.data)/2)
+				for i := range sf.SampleData {
+					sf.SampleData[i] = int16(binary.LittleEndian.Uint16(c.data[2*i:]))
+				}
+			case "pdta/phdr", "pdta/pbag", "pdta/pmod", "pdta/pgen", "pdta/inst", "pdta/ibag", "pdta/imod", "pdta/igen", "pdta/shdr":
+				pdta[c.id] = c.data
+			}
+		}
+	}
+
+	if err := sf.readSamples(pdta); err != nil {
+		return nil, err
+	}
+	if err := sf.readInstruments(pdta); err != nil {
+		return nil, err
+	}
+	if err := sf.readPresets(pdta); err != nil {
+		return nil, err
+	}
+	return sf, nil
+}
+
+// riffChunks splits RIFF data into chunks
+func riffChunks(data []byte) ([]riffChunk, error) {
+	var chunks []riffChunk
+	for len(data) >= 8 {
+		id := string(data[:4])
+		size := binary.LittleEndian.Uint32(data[4:])
+		if uint64(size) > uint64(len(data)-8) {
+			return nil, fmt.Errorf("the %q chunk is longer than the file", id)
+		}
+		chunks = append(chunks, riffChunk{id, data[8 : 8+size]})
+		// Chunks are padded to an even size
+		next := 8 + int(size) + int(size%2)
+		if next > len(data) {
+			next = len(data)
+		}
+		data = data[next:]
+	}
+	return chunks, nil
+}
+
+// soundFontString returns a string that ends with the first zero byte
+func soundFontString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return string(data)
+}
+
+// soundFontRecords splits a chunk into records of the given size, and checks that
+// there is at least one record, for the terminal record
+func soundFontRecords(pdta map[string][]byte, id string, size int) ([][]byte, error) {
+	data := pdta[id]
+	if len(data) < size || len(data)%size != 0 {
+		return nil, fmt.Errorf("invalid %q chunk in SoundFont file", id)
+	}
+	records := make([][]byte, len(data)/size)
+	for i := range records {
+		records[i] = data[i*size : (i+1)*size]
+	}
+	return records, nil
+}
+
+// readSamples reads the sample headers
+func (sf *SoundFont) readSamples(pdta map[string][]byte) error {
+	records, err := soundFontRecords(pdta, "shdr", 46)
+	if err != nil {
+		return err
+	}
+	for _, r := range records[:len(records)-1] {
+		s := &SoundFontSample{
+			Name:            soundFontString(r[:20]),
+			Start:           binary.LittleEndian.Uint32(r[20:]),
+			End:             binary.LittleEndian.Uint32(r[24:]),
+			LoopStart:       binary.LittleEndian.Uint32(r[28:]),
+			LoopEnd:         binary.LittleEndian.Uint32(r[32:]),
+			SampleRate:      binary.LittleEndian.Uint32(r[36:]),
+			OriginalPitch:   r[40],
+			PitchCorrection: int8(r[41]),
+			Link:            binary.LittleEndian.Uint16(r[42:]),
+			Type:            binary.LittleEndian.Uint16(r[44:]),
+		}
+		if s.Start > s.End || int(s.End) > len(sf.SampleData) {
+			return fmt.Errorf("sample %q is outside of the sample data", s.Name)
+		}
+		sf.Samples = append(sf.Samples, s)
+	}
+	return nil
+}
+
+// readInstruments reads the instruments and their zones
+func (sf *SoundFont) readInstruments(pdta map[string][]byte) error {
+	records, err := soundFontRecords(pdta, "inst", 22)
+	if err != nil {
+		return err
+	}
+	zones, err := readSoundFontZones(pdta, "ibag", "igen", "imod")
+	if err != nil {
+		return err
+	}
+	for i, r := range records[:len(records)-1] {
+		instrument := &SoundFontInstrument{Name: soundFontString(r[:20])}
+		first, last := int(binary.LittleEndian.Uint16(r[20:])), int(binary.LittleEndian.Uint16(records[i+1][20:]))
+		if first > last || last > len(zones) {
+			return fmt.Errorf("invalid zones in instrument %q", instrument.Name)
+		}
+		for j, z := range zones[first:last] {
+			id, ok := z.Generator(sfSampleID)
+			switch {
+			case ok && int(uint16(id)) < len(sf.Samples):
+				z.Sample = sf.Samples[uint16(id)]
+				instrument.Zones = append(instrument.Zones, z)
+			case ok:
+				return fmt.Errorf("invalid sample in instrument %q", instrument.Name)
+			case j == 0:
+				instrument.Global = z
+			}
+		}
+		sf.Instruments = append(sf.Instruments, instrument)
+	}
+	return nil
+}
+
+// readPresets reads the presets and their zones
+func (sf *SoundFont) readPresets(pdta map[string][]byte) error {
+	records, err := soundFontRecords(pdta, "phdr", 38)
+	if err != nil {
+		return err
+	}
+	zones, err := readSoundFontZones(pdta, "pbag", "pgen", "pmod")
+	if err != nil {
+		return err
+	}
+	for i, r := range records[:len(records)-1] {
+		preset := &SoundFontPreset{
+			Name:    soundFontString(r[:20]),
+			Program: binary.LittleEndian.Uint16(r[20:]),
+			Bank:    binary.LittleEndian.Uint16(r[22:]),
+		}
+		first, last := int(binary.LittleEndian.Uint16(r[24:])), int(binary.LittleEndian.Uint16(records[i+1][24:]))
+		if first > last || last > len(zones) {
+			return fmt.Errorf("invalid zones in preset %q", preset.Name)
+		}
+		for j, z := range zones[first:last] {
+			id, ok := z.Generator(sfInstrument)
+			switch {
+			case ok && int(uint16(id)) < len(sf.Instruments):
+				z.Instrument = sf.Instruments[uint16(id)]
+				preset.Zones = append(preset.Zones, z)
+			case ok:
+				return fmt.Errorf("invalid instrument in preset %q", preset.Name)
+			case j == 0:
+				preset.Global = z
+			}
+		}
+		sf.Presets = append(sf.Presets, preset)
+	}
+	return nil
+}
+
+// readSoundFontZones reads the zones of the presets or the instruments, with their
+// generators and modulators. The last bag is the terminal record, which is not a zone.
+func readSoundFontZones(pdta map[string][]byte, bagID, genID, modID string) ([]*SoundFontZone, error) {
+	bags, err := soundFontRecords(pdta, bagID, 4)
+	if err != nil {
+		return nil, err
+	}
+	gens, err := soundFontRecords(pdta, genID, 4)
+	if err != nil {
+		return nil, err
+	}
+	mods, err := soundFontRecords(pdta, modID, 10)
+	if err != nil {
+		return nil, err
+	}
+	zones := make([]*SoundFontZone, len(bags)-1)
+	for i := range zones {
+		z := &SoundFontZone{}
+		firstGen, lastGen := int(binary.LittleEndian.Uint16(bags[i])), int(binary.LittleEndian.Uint16(bags[i+1]))
+		firstMod, lastMod := int(binary.LittleEndian.Uint16(bags[i][2:])), int(binary.LittleEndian.Uint16(bags[i+1][2:]))
+		if firstGen > lastGen || lastGen > len(gens) || firstMod > lastMod || lastMod > len(mods) {
+			return nil, fmt.Errorf("invalid %q chunk in SoundFont file", bagID)
+		}
+		for _, g := range gens[firstGen:lastGen] {
+			z.Generators = append(z.Generators, SoundFontGenerator{
+				Operator: binary.LittleEndian.Uint16(g),
+				Amount:   int16(binary.LittleEndian.Uint16(g[2:])),
+			})
+		}
+		for _, m := range mods[firstMod:lastMod] {
+			z.Modulators = append(z.Modulators, SoundFontModulator{
+				Source:       binary.LittleEndian.Uint16(m),
+				Destination:  binary.LittleEndian.Uint16(m[2:]),
+				Amount:       int16(binary.LittleEndian.Uint16(m[4:])),
+				AmountSource: binary.LittleEndian.Uint16(m[6:]),
+				Transform:    binary.LittleEndian.Uint16(m[8:]),
+			})
+		}
+		zones[i] = z
+	}
+	return zones, nil
+}
+
+// Preset returns the preset with the given bank and program. If there is none, the
+// program in bank 0 is used, or the first preset in the bank for percussion.
+func (sf *SoundFont) Preset(bank, program uint16) *SoundFontPreset {
+	var fallback *SoundFontPreset
+	for _, p := range sf.Presets {
+		if p.Bank == bank && p.Program == program {
+			return p
+		}
+		if fallback == nil && (bank == 128 && p.Bank == 128 || bank != 128 && p.Bank == 0 && p.Program == program) {
+			fallback = p
+		}
+	}
+	return fallback
+}
+
+// SoundFontSynth renders MIDI with the presets and samples of a SoundFont. It uses
+// the key and velocity ranges, the tuning, the sample loops and the volume envelope
+// of the zones, but not the modulators, filters or effects.
+type SoundFontSynth struct {
+	SoundFont  *SoundFont
+	SampleRate int
+	Gain       float64 // The volume of a sample with the highest velocity and no attenuation
+}
+
+// NewSoundFontSynth creates a new SoundFontSynth
+func NewSoundFontSynth(sf *SoundFont, sampleRate int) *SoundFontSynth {
+	return &SoundFontSynth{SoundFont: sf, SampleRate: sampleRate, Gain: 0.5}
+}
+
+// Render renders the events in all the tracks of a MIDI song, to mono samples from
+// -1 to 1. Program changes and bank select controllers select the presets, and the
+// drum channel uses bank 128.
+func (s *SoundFontSynth) Render(m *MIDI) []float64 {
+	return renderEvents(m, s.SampleRate, s.start)
+}
+
+// WriteWAV renders the events in all the tracks of a MIDI song, and writes them as a WAV file
+func (s *SoundFontSynth) WriteWAV(w io.Writer, m *MIDI) error {
+	return WriteWAV(w, s.Render(m), s.SampleRate)
+}
+
+// start returns the sounds of a new note, one for each zone that matches the key and velocity
+func (s *SoundFontSynth) start(channel, key, velocity uint8, frequency float64, program uint8, bank uint16) []sound {
+	preset := s.SoundFont.Preset(bank, uint16(program))
+	if preset == nil {
+		return nil
+	}
+	// The difference from equal temperament, in semitones
+	tuning := 12 * math.Log2(frequency/midiNumberToFrequency(float64(key)))
+
+	var sounds []sound
+	for _, presetZone := range preset.Zones {
+		presetGens := zoneGenerators(nil, preset.Global, presetZone)
+		if !inSoundFontRange(presetGens, key, velocity) {
+			continue
+		}
+		instrument := presetZone.Instrument
+		for _, instrumentZone := range instrument.Zones {
+			gens := zoneGenerators(soundFontDefaults, instrument.Global, instrumentZone)
+			if !inSoundFontRange(gens, key, velocity) {
+				continue
+			}
+			// Preset generators are added to the instrument generators
+			for operator, amount := range presetGens {
+				switch operator {
+				case sfInstrument, sfKeyRange, sfVelRange, sfSampleID, sfSampleModes, sfKeynum, sfVelocity, sfOverridingRootKey,
+					sfStartAddrsOffset, sfEndAddrsOffset, sfStartloopAddrsOffset, sfEndloopAddrsOffset,
+					sfStartAddrsCoarseOffset, sfEndAddrsCoarseOffset, sfStartloopAddrsCoarseOffset, sfEndloopAddrsCoarseOffset:
+				default:
+					gens[operator] += amount
+				}
+			}
+			if v := s.newSampleVoice(instrumentZone.Sample, gens, key, velocity, tuning); v != nil {
+				sounds = append(sounds, v)
+			}
+		}
+	}
+	return sounds
+}
+
+// zoneGenerators returns the generators of a zone, which replace the generators of
+// the global zone, which replace the defaults
+func zoneGenerators(defaults map[uint16]int16, global, zone *SoundFontZone) map[uint16]int16 {
+	gens := make(map[uint16]int16)
+	for operator, amount := range defaults {
+		gens[operator] = amount
+	}
+	for _, z := range []*SoundFontZone{global, zone} {
+		if z == nil {
+			continue
+		}
+		for _, g := range z.Generators {
+			gens[g.Operator] = g.Amount
+		}
+	}
+	return gens
+}
+
+// inSoundFontRange checks if a key and velocity are in the ranges of the generators
+func inSoundFontRange(gens map[uint16]int16, key, velocity uint8) bool {
+	for operator, value := range map[uint16]uint8{sfKeyRange: key, sfVelRange: velocity} {
+		amount, ok := gens[operator]
+		if !ok {
+			continue
+		}
+		low, high := uint8(uint16(amount)&0xFF), uint8(uint16(amount)>>8)
+		if value < low || value > high {
+			return false
+		}
+	}
+	return true
+}
+
+// timecents converts SoundFont timecents to seconds
+func timecents(amount int16) float64 {
+	return math.Pow(2, float64(amount)/1200)
+}
+
+// sampleVoice is a note that is playing a SoundFont sample
+type sampleVoice struct {
+	data      []int16
+	position  float64 // the position in the sample data
+	step      float64 // the number of samples to move for each output sample, without pitch bend
+	end       int
+	loopStart int
+	loopEnd   int
+	loopMode  int16 // 0 for no loop, 1 to loop, and 3 to loop until the note is released
+	amplitude float64
+
+	rate                       float64 // the output sample rate
+	delay, attack, hold, decay float64 // the volume envelope, in seconds
+	sustain, releaseTime       float64
+	age                        int     // the number of samples that have been rendered
+	releaseAge                 int     // the age when the note was released, or -1 if it is held
+	releaseLevel               float64 // the envelope level when the note was released
+	finished                   bool    // the end of the sample was reached
+}
+
+// newSampleVoice creates a new sampleVoice from the generators of a zone, or nil if the sample is empty
+func (s *SoundFontSynth) newSampleVoice(sample *SoundFontSample, gens map[uint16]int16, key, velocity uint8, tuning float64) *sampleVoice {
+	address := func(base uint32, fine, coarse uint16) int {
+		a := int(base) + int(gens[fine]) + 32768*int(gens[coarse])
+		if a < 0 {
+			return 0
+		}
+		if a > len(s.SoundFont.SampleData) {
+			return len(s.SoundFont.SampleData)
+		}
+		return a
+	}
+	start := address(sample.Start, sfStartAddrsOffset, sfStartAddrsCoarseOffset)
+	end := address(sample.End, sfEndAddrsOffset, sfEndAddrsCoarseOffset)
+	if end-start < 2 || sample.SampleRate == 0 {
+		return nil
+	}
+
+	if keynum := gens[sfKeynum]; keynum >= 0 {
+		key = uint8(keynum)
+	}
+	if v := gens[sfVelocity]; v >= 0 {
+		velocity = uint8(v)
+	}
+	root := int(sample.OriginalPitch)
+	if r := gens[sfOverridingRootKey]; r >= 0 {
+		root = int(r)
+	}
+	semitones := float64(int(key)-root)*float64(gens[sfScaleTuning])/100 + float64(gens[sfCoarseTune]) +
+		float64(gens[sfFineTune])/100 + float64(sample.PitchCorrection)/100 + tuning
+
+	v := &sampleVoice{
+		data:        s.SoundFont.SampleData,
+		position:    float64(start),
+		step:        float64(sample.SampleRate) / float64(s.SampleRate) * math.Pow(2, semitones/12),
+		end:         end,
+		loopStart:   address(sample.LoopStart, sfStartloopAddrsOffset, sfStartloopAddrsCoarseOffset),
+		loopEnd:     address(sample.LoopEnd, sfEndloopAddrsOffset, sfEndloopAddrsCoarseOffset),
+		loopMode:    gens[sfSampleModes] & 3,
+		amplitude:   s.Gain * math.Pow(10, -float64(gens[sfInitialAttenuation])/200) * math.Pow(float64(velocity)/127, 2),
+		rate:        float64(s.SampleRate),
+		delay:       timecents(gens[sfDelayVolEnv]),
+		attack:      timecents(gens[sfAttackVolEnv]),
+		hold:        timecents(gens[sfHoldVolEnv]),
+		decay:       timecents(gens[sfDecayVolEnv]),
+		sustain:     math.Pow(10, -math.Max(0, float64(gens[sfSustainVolEnv]))/200),
+		releaseTime: timecents(gens[sfReleaseVolEnv]),
+		releaseAge:  -1,
+	}
+	if v.loopEnd-v.loopStart < 2 || v.loopStart < start || v.loopEnd > end {
+		v.loopMode = 0
+	}
+	return v
+}
+
+// looping checks if the voice loops the sample now
+func (v *sampleVoice) looping() bool {
+	return v.loopMode == 1 || v.loopMode == 3 && v.releaseAge < 0
+}
+
+// level returns the current level of the volume envelope
+func (v *sampleVoice) level() float64 {
+	t := float64(v.age) / v.rate
+	if v.releaseAge >= 0 {
+		since := float64(v.age-v.releaseAge) / v.rate
+		if since >= v.releaseTime {
+			return 0
+		}
+		return v.releaseLevel * (1 - since/v.releaseTime)
+	}
+	switch {
+	case t < v.delay:
+		return 0
+	case t < v.delay+v.attack:
+		return (t - v.delay) / v.attack
+	case t < v.delay+v.attack+v.hold:
+		return 1
+	case t < v.delay+v.attack+v.hold+v.decay:
+		return 1 - (1-v.sustain)*(t-v.delay-v.attack-v.hold)/v.decay
+	}
+	return v.sustain
+}
+
+// release starts the release of the volume envelope, if the note is held
+func (v *sampleVoice) release() {
+	if v.releaseAge < 0 {
+		v.releaseLevel = v.level()
+		v.releaseAge = v.age
+	}
+}
+
+// done checks if the voice is released and silent, or if the sample has ended
+func (v *sampleVoice) done() bool {
+	return v.finished || v.releaseAge >= 0 && float64(v.age-v.releaseAge)/v.rate >= v.releaseTime
+}
+
+// next returns the next sample of the voice, with linear interpolation, and the
+// pitch bent by the given semitones
+func (v *sampleVoice) next(bend float64) float64 {
+	if v.finished {
+		return 0
+	}
+	i := int(v.position)
+	fraction := v.position - float64(i)
+	j := i + 1
+	if v.looping() && j >= v.loopEnd {
+		j = v.loopStart
+	} else if j >= v.end {
+		j = i
+	}
+	value := (float64(v.data[i])*(1-fraction) + float64(v.data[j])*fraction) / 32768
+	value *= v.level() * v.amplitude
+	v.age++
+
+	v.position += v.step * math.Pow(2, bend/12)
+	if v.looping() {
+		for v.position >= float64(v.loopEnd) {
+			v.position -= float64(v.loopEnd - v.loopStart)
+		}
+	} else if v.position >= float64(v.end) {
+		v.finished = true
+	}
+	return value
+}
@@ -0,0 +1,174 @@
+package midi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// riff returns a RIFF chunk, padded to an even size
+func riff(id string, parts ...[]byte) []byte {
+	data := bytes.Join(parts, nil)
+	chunk := append([]byte(id), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// le returns the values as little endian bytes
+func le(values ...any) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			name := make([]byte, 20)
+			copy(name, s)
+			buf.Write(name)
+			continue
+		}
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	return buf.Bytes()
+}
+
+// testSoundFont returns a SoundFont file with one looped sine wave sample of 440Hz
+// at 44000Hz, for key 69, used by one instrument and by a piano and a drum preset
+func testSoundFont() []byte {
+	const cycle, cycles = 100, 10
+	var smpl []byte
+	for i := 0; i < cycle*cycles; i++ {
+		smpl = append(smpl, le(int16(math.Round(math.Sin(2*math.Pi*float64(i)/cycle)*16384)))...)
+	}
+	smpl = append(smpl, make([]byte, 2*46)...)
+
+	shdr := bytes.Join([][]byte{
+		le("Sine", uint32(0), uint32(cycle*cycles), uint32(0), uint32(cycle*cycles), uint32(44000), uint8(69), int8(0), uint16(0), uint16(1)),
+		le("EOS", uint32(0), uint32(0), uint32(0), uint32(0), uint32(0), uint8(0), int8(0), uint16(0), uint16(0)),
+	}, nil)
+	// A global zone with a release time of 0.5 seconds, and a zone with a looped sample for keys 0 to 100
+	igen := le(
+		uint16(sfReleaseVolEnv), int16(-1200),
+		uint16(sfKeyRange), uint8(0), uint8(100), uint16(sfSampleModes), int16(1), uint16(sfSampleID), uint16(0),
+		uint16(0), uint16(0),
+	)
+	ibag := le(uint16(0), uint16(0), uint16(1), uint16(0), uint16(4), uint16(1))
+	imod := le(uint16(0x0502), uint16(sfInitialAttenuation), int16(960), uint16(0), uint16(0), make([]byte, 10))
+	inst := le("Sine", uint16(0), "EOI", uint16(2))
+	// The piano preset is tuned 12 semitones up
+	pgen := le(uint16(sfCoarseTune), int16(12), uint16(sfInstrument), uint16(0), uint16(sfInstrument), uint16(0), uint16(0), uint16(0))
+	pbag := le(uint16(0), uint16(0), uint16(2), uint16(0), uint16(3), uint16(0))
+	pmod := make([]byte, 10)
+	phdr := le(
+		"Piano", uint16(0), uint16(0), uint16(0), uint32(0), uint32(0), uint32(0),
+		"Drums", uint16(0), uint16(128), uint16(1), uint32(0), uint32(0), uint32(0),
+		"EOP", uint16(0), uint16(0), uint16(2), uint32(0), uint32(0), uint32(0),
+	)
+
+	return riff("RIFF", []byte("sfbk"),
+		riff("LIST", []byte("INFO"), riff("ifil", le(uint16(2), uint16(1))), riff("INAM", []byte("Test\x00"))),
+		riff("LIST", []byte("sdta"), riff("smpl", smpl)),
+		riff("LIST", []byte("pdta"),
+			riff("phdr", phdr), riff("pbag", pbag), riff("pmod", pmod), riff("pgen", pgen),
+			riff("inst", inst), riff("ibag", ibag), riff("imod", imod), riff("igen", igen), riff("shdr", shdr)),
+	)
+}
+
+func TestReadSoundFont(t *testing.T) {
+	sf, err := ReadSoundFont(bytes.NewReader(testSoundFont()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.Name != "Test" || sf.Version != [2]uint16{2, 1} {
+		t.Errorf("name and version = %q and %v, want \"Test\" and [2 1]", sf.Name, sf.Version)
+	}
+	if len(sf.SampleData) != 1046 || len(sf.Samples) != 1 || sf.Samples[0].OriginalPitch != 69 {
+		t.Fatalf("unexpected samples: %d samples in %d sample headers", len(sf.SampleData), len(sf.Samples))
+	}
+
+	if len(sf.Instruments) != 1 {
+		t.Fatalf("got %d instruments, want 1", len(sf.Instruments))
+	}
+	instrument := sf.Instruments[0]
+	if instrument.Global == nil || len(instrument.Zones) != 1 || instrument.Zones[0].Sample != sf.Samples[0] {
+		t.Fatalf("the instrument should have a global zone and a zone with the sample")
+	}
+	if amount, ok := instrument.Zones[0].Generator(sfKeyRange); !ok || amount != 100<<8 {
+		t.Errorf("key range = %X, want %X", amount, 100<<8)
+	}
+	if len(instrument.Zones[0].Modulators) != 1 || instrument.Zones[0].Modulators[0].Amount != 960 {
+		t.Errorf("unexpected modulators: %+v", instrument.Zones[0].Modulators)
+	}
+
+	if len(sf.Presets) != 2 {
+		t.Fatalf("got %d presets, want 2", len(sf.Presets))
+	}
+	if p := sf.Presets[0]; p.Name != "Piano" || len(p.Zones) != 1 || p.Zones[0].Instrument != instrument || p.Global != nil {
+		t.Errorf("unexpected piano preset: %+v", p)
+	}
+	if p := sf.Preset(0, 5); p != nil {
+		t.Errorf("expected no preset for program 5, got %q", p.Name)
+	}
+	if p := sf.Preset(128, 25); p == nil || p.Name != "Drums" {
+		t.Errorf("expected the drum preset for bank 128")
+	}
+	if p := sf.Preset(3, 0); p == nil || p.Name != "Piano" {
+		t.Errorf("expected the piano preset in bank 0 for a missing bank")
+	}
+}
+
+func TestReadSoundFontErrors(t *testing.T) {
+	if _, err := ReadSoundFont(strings.NewReader("RIFF\x04\x00\x00\x00WAVE")); err == nil {
+		t.Errorf("expected an error for a file that is not a SoundFont")
+	}
+	data := testSoundFont()
+	if _, err := ReadSoundFont(bytes.NewReader(data[:len(data)-10])); err == nil {
+		t.Errorf("expected an error for a truncated file")
+	}
+}
+
+func TestSoundFontSynth(t *testing.T) {
+	sf, err := ReadSoundFont(bytes.NewReader(testSoundFont()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const rate = 44000
+	m := NewMIDI(0, 96, 120)
+	track := NewTrack()
+	// The piano preset plays A4 as A5, and the drum preset plays it as A4
+	if err := m.AddNote(track, &Note{Frequency: 440, Duration: time.Second / 2, Velocity: 127, Channel: 1, Program: 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddNote(track, &Note{Frequency: 440, Duration: time.Second / 2, Velocity: 127, Channel: DrumChannel, EventDelay: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	// Key 105 is outside of the key range of the instrument
+	if err := m.AddNote(track, &Note{Frequency: midiNumberToFrequency(105), Duration: time.Second / 2, Velocity: 127, Channel: 1, EventDelay: 2 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	m.AddTrack(track)
+
+	s := NewSoundFontSynth(sf, rate)
+	samples := s.Render(m)
+	// The last sounding note ends after 1.5 seconds, and is released for 0.5 seconds
+	if len(samples) < 2*rate || peak(samples[2*rate:]) != 0 {
+		t.Errorf("got %d samples, expected silence after 2 seconds", len(samples))
+	}
+	if f := measureFrequency(samples, rate/10, rate*4/10, rate); math.Abs(f-880) > 0.1 {
+		t.Errorf("piano frequency = %.2f, want 880", f)
+	}
+	if f := measureFrequency(samples, rate*11/10, rate*14/10, rate); math.Abs(f-440) > 0.1 {
+		t.Errorf("drum frequency = %.2f, want 440", f)
+	}
+	if p := peak(samples[rate/10 : rate*4/10]); math.Abs(p-s.Gain/2) > 0.01 {
+		t.Errorf("peak = %f, want %f", p, s.Gain/2)
+	}
+	// The release makes the note fade out after it ends
+	if p := peak(samples[rate*6/10 : rate*7/10]); p == 0 || p > s.Gain/2 {
+		t.Errorf("release peak = %f, want between 0 and %f", p, s.Gain/2)
+	}
+}
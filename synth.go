@@ -0,0 +1,229 @@
+package midi
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Waveform is the shape of the sound wave of an oscillator
+type Waveform int
+
+// Oscillator waveforms
+const (
+	Sine Waveform = iota
+	Square
+	Sawtooth
+	Triangle
+	Noise
+)
+
+// Envelope is an ADSR envelope, which shapes the volume of a note over time
+type Envelope struct {
+	Attack  time.Duration // The time from silence to full volume
+	Decay   time.Duration // The time from full volume to the sustain level
+	Sustain float64       // The volume while the note is held, from 0 to 1
+	Release time.Duration // The time from the current volume to silence, after the note is released
+}
+
+// Patch is the sound of a program in a Synth
+type Patch struct {
+	Waveform Waveform
+	Envelope Envelope
+}
+
+// Synth is a simple polyphonic software synthesizer, which renders MIDI to mono
+// samples from -1 to 1, for previewing songs without a hardware synthesizer
+type Synth struct {
+	SampleRate int
+	Gain       float64         // The volume of a note with the highest velocity
+	Patches    map[uint8]Patch // The patches of programs, by program number
+	Default    Patch           // The patch of programs that are not in Patches
+	Drums      Patch           // The patch of the drum channel
+
+	rand *rand.Rand
+}
+
+// NewSynth creates a new Synth with sine waves for all programs, and noise for the drum channel
+func NewSynth(sampleRate int) *Synth {
+	return &Synth{
+		SampleRate: sampleRate,
+		Gain:       0.25,
+		Patches:    make(map[uint8]Patch),
+		Default: Patch{
+			Waveform: Sine,
+			Envelope: Envelope{Attack: 10 * time.Millisecond, Decay: 100 * time.Millisecond, Sustain: 0.7, Release: 100 * time.Millisecond},
+		},
+		Drums: Patch{
+			Waveform: Noise,
+			Envelope: Envelope{Attack: time.Millisecond, Decay: 150 * time.Millisecond, Sustain: 0, Release: 50 * time.Millisecond},
+		},
+		rand: rand.New(rand.NewSource(1)),
+	}
+}
+
+// Render renders the events in all the tracks of a MIDI song. The keys are tuned
+// with m.Tuning if it is set without a pitch bend range, and pitch bend events
+// bend the notes by up to m.PitchBendRange semitones, or 2 semitones if it is not set.
+func (s *Synth) Render(m *MIDI) []float64 {
+	return renderEvents(m, s.SampleRate, func(channel, key, velocity uint8, frequency float64, program uint8, bank uint16) []sound {
+		return []sound{s.newVoice(frequency, velocity, s.patch(channel, program))}
+	})
+}
+
+// RenderNotes renders notes with the exact frequency of each note, or the
+// frequency of the pitch with equal temperament, from A4 at 440Hz. Each note
+// starts at its EventDelay. An error is returned for a negative EventDelay or Duration.
+func (s *Synth) RenderNotes(notes []*Note) ([]float64, error) {
+	var samples []float64
+	for _, note := range notes {
+		if note.EventDelay < 0 || note.Duration < 0 {
+			return nil, fmt.Errorf("invalid note timing, the delay %v and the duration %v must not be negative", note.EventDelay, note.Duration)
+		}
+		channel, program, frequency := note.Channel, note.Program, note.Frequency
+		if note.Instrument != "" {
+			var err error
+			// The GM2 variations sound like their programs
+			if program, _, err = ProgramVariationByName(note.Instrument); err != nil {
+				return nil, err
+			}
+		}
+		if note.Pitch != nil {
+			frequency = note.Pitch.Frequency(nil)
+		}
+		if note.Drum != "" {
+			if _, err := DrumKey(note.Drum); err != nil {
+				return nil, err
+			}
+			channel = DrumChannel
+		}
+
+		v := s.newVoice(frequency, note.Velocity, s.patch(channel, program))
+		start := s.sampleAt(note.EventDelay)
+		releaseAt := start + s.sampleAt(note.Duration)
+		for i := start; ; i++ {
+			if i == releaseAt {
+				v.release()
+			}
+			if v.done() {
+				break
+			}
+			for len(samples) <= i {
+				samples = append(samples, 0)
+			}
+			samples[i] += v.next(0)
+		}
+	}
+	return samples, nil
+}
+
+// WriteWAV renders the events in all the tracks of a MIDI song, and writes them as a WAV file
+func (s *Synth) WriteWAV(w io.Writer, m *MIDI) error {
+	return WriteWAV(w, s.Render(m), s.SampleRate)
+}
+
+// patch returns the patch of a program, or of the drum channel
+func (s *Synth) patch(channel, program uint8) Patch {
+	if channel == DrumChannel {
+		return s.Drums
+	}
+	if patch, ok := s.Patches[program]; ok {
+		return patch
+	}
+	return s.Default
+}
+
+// sampleAt returns the number of samples in a duration
+func (s *Synth) sampleAt(d time.Duration) int {
+	return int(math.Round(d.Seconds() * float64(s.SampleRate)))
+}
+
+// voice is a note that is playing in a Synth
+type voice struct {
+	frequency    float64 // the frequency without pitch bend
+	amplitude    float64
+	patch        Patch
+	rate         float64 // the sample rate
+	phase        float64 // the position in the wave, from 0 to 1
+	age          int     // the number of samples that have been rendered
+	releaseAge   int     // the age when the note was released, or -1 if it is held
+	releaseLevel float64 // the envelope level when the note was released
+	rand         *rand.Rand
+}
+
+// newVoice creates a new voice, where the velocity scales the volume
+func (s *Synth) newVoice(frequency float64, velocity uint8, patch Patch) *voice {
+	return &voice{
+		frequency:  frequency,
+		amplitude:  s.Gain * float64(velocity) / 127,
+		patch:      patch,
+		rate:       float64(s.SampleRate),
+		releaseAge: -1,
+		rand:       s.rand,
+	}
+}
+
+// level returns the envelope level at a time after the start of a held note
+func (e Envelope) level(t float64) float64 {
+	attack, decay := e.Attack.Seconds(), e.Decay.Seconds()
+	if t < attack {
+		return t / attack
+	}
+	if t -= attack; t < decay {
+		return 1 - (1-e.Sustain)*t/decay
+	}
+	return e.Sustain
+}
+
+// level returns the current envelope level of the voice
+func (v *voice) level() float64 {
+	if v.releaseAge < 0 {
+		return v.patch.Envelope.level(float64(v.age) / v.rate)
+	}
+	release := v.patch.Envelope.Release.Seconds()
+	since := float64(v.age-v.releaseAge) / v.rate
+	if since >= release {
+		return 0
+	}
+	return v.releaseLevel * (1 - since/release)
+}
+
+// release starts the release of the envelope, if the note is held
+func (v *voice) release() {
+	if v.releaseAge < 0 {
+		v.releaseLevel = v.level()
+		v.releaseAge = v.age
+	}
+}
+
+// done checks if the voice is released and silent
+func (v *voice) done() bool {
+	return v.releaseAge >= 0 && float64(v.age-v.releaseAge)/v.rate >= v.patch.Envelope.Release.Seconds()
+}
+
+// next returns the next sample of the voice, with the pitch bent by the given semitones
+func (v *voice) next(bend float64) float64 {
+	var value float64
+	switch v.patch.Waveform {
+	case Sine:
+		value = math.Sin(2 * math.Pi * v.phase)
+	case Square:
+		value = 1
+		if v.phase >= 0.5 {
+			value = -1
+		}
+	case Sawtooth:
+		value = 2*v.phase - 1
+	case Triangle:
+		value = 1 - 4*math.Abs(v.phase-0.5)
+	case Noise:
+		value = 2*v.rand.Float64() - 1
+	}
+	value *= v.level() * v.amplitude
+	v.phase += v.frequency * math.Pow(2, bend/12) / v.rate
+	v.phase -= math.Floor(v.phase)
+	v.age++
+	return value
+}
@@ -0,0 +1,130 @@
+package midi
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+// measureFrequency returns the frequency of a signal between two samples, from the
+// time between the first and the last rising zero crossing
+func measureFrequency(samples []float64, from, to, sampleRate int) float64 {
+	first, last := -1.0, -1.0
+	crossings := 0
+	for i := from + 1; i < to; i++ {
+		if samples[i-1] < 0 && samples[i] >= 0 {
+			t := float64(i-1) + samples[i-1]/(samples[i-1]-samples[i])
+			if first < 0 {
+				first = t
+			}
+			last = t
+			crossings++
+		}
+	}
+	return float64(crossings-1) / ((last - first) / float64(sampleRate))
+}
+
+// peak returns the highest absolute value of the samples
+func peak(samples []float64) float64 {
+	var p float64
+	for _, sample := range samples {
+		p = math.Max(p, math.Abs(sample))
+	}
+	return p
+}
+
+func TestSynthRender(t *testing.T) {
+	const rate = 44100
+	m := NewMIDI(0, 96, 120)
+	track := NewTrack()
+	if err := m.AddNote(track, &Note{Frequency: 440, Duration: time.Second / 2, Velocity: 127, Channel: 1}); err != nil {
+		t.Fatal(err)
+	}
+	m.AddTrack(track)
+
+	s := NewSynth(rate)
+	samples := s.Render(m)
+	// The note and the release of the envelope
+	if want := rate/2 + rate/10; len(samples) != want {
+		t.Errorf("got %d samples, want %d", len(samples), want)
+	}
+	if f := measureFrequency(samples, rate/10, rate*4/10, rate); math.Abs(f-440) > 0.1 {
+		t.Errorf("frequency = %.2f, want 440", f)
+	}
+	if p := peak(samples); math.Abs(p-s.Gain) > 0.001 {
+		t.Errorf("peak = %f, want %f", p, s.Gain)
+	}
+	// The sustain level
+	if p := peak(samples[rate/4 : rate/2]); math.Abs(p-s.Gain*0.7) > 0.001 {
+		t.Errorf("sustain peak = %f, want %f", p, s.Gain*0.7)
+	}
+}
+
+func TestSynthPitchBend(t *testing.T) {
+	const rate = 44100
+	m := NewMIDI(0, 96, 120)
+	m.PitchBendRange = 2
+	track := NewTrack()
+	if err := m.AddNote(track, &Note{Frequency: 445, Duration: time.Second / 2, Velocity: 100, Channel: 1}); err != nil {
+		t.Fatal(err)
+	}
+	m.AddTrack(track)
+
+	samples := NewSynth(rate).Render(m)
+	if f := measureFrequency(samples, rate/10, rate*4/10, rate); math.Abs(f-445) > 0.1 {
+		t.Errorf("frequency = %.2f, want 445", f)
+	}
+}
+
+func TestSynthRenderNotes(t *testing.T) {
+	const rate = 8000
+	s := NewSynth(rate)
+	s.Patches[80] = Patch{Waveform: Square, Envelope: Envelope{Sustain: 1}}
+	notes := []*Note{
+		{Frequency: 261.63, Duration: time.Second / 4, Velocity: 127, Program: 80},
+		{Frequency: 261.63, Duration: time.Second / 4, Velocity: 64, Program: 80, EventDelay: time.Second / 2},
+	}
+	samples, err := s.RenderNotes(notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != rate*3/4 {
+		t.Errorf("got %d samples, want %d", len(samples), rate*3/4)
+	}
+	loud, soft := peak(samples[:rate/4]), peak(samples[rate/2:])
+	if math.Abs(soft/loud-64.0/127) > 0.001 {
+		t.Errorf("the volume should follow the velocity, got %f and %f", loud, soft)
+	}
+	if p := peak(samples[rate/4 : rate/2]); p != 0 {
+		t.Errorf("expected silence between the notes, got %f", p)
+	}
+	if _, err := s.RenderNotes([]*Note{{Drum: "Cowbel"}}); err == nil {
+		t.Errorf("expected an error for an unknown drum")
+	}
+	for _, note := range []*Note{
+		{Frequency: 440, Duration: time.Second, EventDelay: -time.Millisecond},
+		{Frequency: 440, Duration: -time.Second},
+	} {
+		if _, err := s.RenderNotes([]*Note{note}); err == nil {
+			t.Errorf("expected an error for a negative delay or duration, for %+v", note)
+		}
+	}
+}
+
+func TestSynthWriteWAV(t *testing.T) {
+	m := NewMIDI(0, 96, 120)
+	track := NewTrack()
+	if err := m.AddNote(track, &Note{Drum: "Acoustic Snare", Duration: time.Second / 4, Velocity: 100}); err != nil {
+		t.Fatal(err)
+	}
+	m.AddTrack(track)
+
+	var buf bytes.Buffer
+	if err := NewSynth(22050).WriteWAV(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("RIFF")) || string(buf.Bytes()[8:16]) != "WAVEfmt " {
+		t.Errorf("not a WAV file: %q", buf.Bytes()[:16])
+	}
+}
@@ -0,0 +1,121 @@
+package midi
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// TempoMap converts between absolute ticks and time, for a song that may change tempo
+type TempoMap struct {
+	division uint16
+	changes  []tempoChange
+}
+
+// tempoChange is a tempo that starts at the given tick
+type tempoChange struct {
+	tick         uint32
+	microseconds float64       // microseconds per quarter note
+	start        time.Duration // the time at the tick
+}
+
+// NewTempoMap creates a new TempoMap with the given time division and starting tempo
+func NewTempoMap(division uint16, bpm float64) *TempoMap {
+	if bpm <= 0 {
+		bpm = DefaultBPM
+	}
+	return &TempoMap{
+		division: division,
+		changes:  []tempoChange{{tick: 0, microseconds: 60000000.0 / bpm}},
+	}
+}
+
+// TempoMap creates a TempoMap from the BPM and the Set Tempo events in all the tracks
+func (m *MIDI) TempoMap() *TempoMap {
+	tm := NewTempoMap(m.Division, m.BPM)
+	for _, t := range m.Tracks {
+		for _, e := range t.Events {
+			if bpm, ok := e.Tempo(); ok {
+				tm.SetTempo(e.Tick, bpm)
+			}
+		}
+	}
+	return tm
+}
+
+// SetTempo changes the tempo from the given tick and onwards, until the next tempo change
+func (tm *TempoMap) SetTempo(tick uint32, bpm float64) {
+	if bpm <= 0 {
+		return
+	}
+	change := tempoChange{tick: tick, microseconds: 60000000.0 / bpm}
+	i := sort.Search(len(tm.changes), func(i int) bool {
+		return tm.changes[i].tick >= tick
+	})
+	if i < len(tm.changes) && tm.changes[i].tick == tick {
+		tm.changes[i] = change
+	} else {
+		tm.changes = append(tm.changes, tempoChange{})
+		copy(tm.changes[i+1:], tm.changes[i:])
+		tm.changes[i] = change
+	}
+
+	// Update the start times of the tempo changes
+	for i := 1; i < len(tm.changes); i++ {
+		previous := tm.changes[i-1]
+		ticks := float64(tm.changes[i].tick - previous.tick)
+		tm.changes[i].start = previous.start + time.Duration(math.Round(ticks*tm.tickNanoseconds(previous)))
+	}
+}
+
+// Tempo returns the tempo at the given tick, in BPM
+func (tm *TempoMap) Tempo(tick uint32) float64 {
+	return 60000000.0 / tm.changes[tm.changeAtTick(tick)].microseconds
+}
+
+// SMPTE checks if the time division is in SMPTE frames instead of ticks per quarter note.
+// In that case the tempo has no effect on the timing.
+func (tm *TempoMap) SMPTE() bool {
+	return tm.division&0x8000 != 0
+}
+
+// TicksToDuration converts an absolute tick to the time from the start of the song
+func (tm *TempoMap) TicksToDuration(tick uint32) time.Duration {
+	change := tm.changes[tm.changeAtTick(tick)]
+	ticks := float64(tick - change.tick)
+	return change.start + time.Duration(math.Round(ticks*tm.tickNanoseconds(change)))
+}
+
+// DurationToTicks converts a time from the start of the song to an absolute tick
+func (tm *TempoMap) DurationToTicks(d time.Duration) uint32 {
+	if d <= 0 {
+		return 0
+	}
+	i := sort.Search(len(tm.changes), func(i int) bool {
+		return tm.changes[i].start > d
+	}) - 1
+	change := tm.changes[i]
+	ticks := float64(d-change.start) / tm.tickNanoseconds(change)
+	return change.tick + uint32(math.Round(ticks))
+}
+
+// changeAtTick returns the index of the tempo change that is in effect at the given tick
+func (tm *TempoMap) changeAtTick(tick uint32) int {
+	return sort.Search(len(tm.changes), func(i int) bool {
+		return tm.changes[i].tick > tick
+	}) - 1
+}
+
+// tickNanoseconds returns the length of one tick, in nanoseconds
+func (tm *TempoMap) tickNanoseconds(change tempoChange) float64 {
+	if tm.SMPTE() {
+		// The upper byte is the negative number of frames per second, and the lower byte is the ticks per frame
+		framesPerSecond := float64(-int8(tm.division >> 8))
+		if framesPerSecond == 29 {
+			framesPerSecond = 29.97 // 30 fps drop frame
+		}
+		ticksPerFrame := float64(tm.division & 0xFF)
+		return float64(time.Second) / (framesPerSecond * ticksPerFrame)
+	}
+	return change.microseconds * 1000 / float64(tm.division)
+}
@@ -0,0 +1,81 @@
+package midi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTempoMapConstant(t *testing.T) {
+	tm := NewTempoMap(480, 120) // 960 ticks per second
+	if ticks := tm.DurationToTicks(1500 * time.Millisecond); ticks != 1440 {
+		t.Errorf("DurationToTicks(1.5s) = %d, want 1440", ticks)
+	}
+	if d := tm.TicksToDuration(1440); d != 1500*time.Millisecond {
+		t.Errorf("TicksToDuration(1440) = %v, want 1.5s", d)
+	}
+}
+
+func TestTempoMapChanges(t *testing.T) {
+	tm := NewTempoMap(480, 120)
+	tm.SetTempo(960, 60)   // after 1 second, 480 ticks per second
+	tm.SetTempo(1920, 240) // after 3 seconds, 1920 ticks per second
+
+	tests := []struct {
+		tick uint32
+		d    time.Duration
+	}{
+		{0, 0},
+		{960, time.Second},
+		{1440, 2 * time.Second},
+		{1920, 3 * time.Second},
+		{3840, 4 * time.Second},
+	}
+	for _, test := range tests {
+		if d := tm.TicksToDuration(test.tick); d != test.d {
+			t.Errorf("TicksToDuration(%d) = %v, want %v", test.tick, d, test.d)
+		}
+		if tick := tm.DurationToTicks(test.d); tick != test.tick {
+			t.Errorf("DurationToTicks(%v) = %d, want %d", test.d, tick, test.tick)
+		}
+	}
+	if bpm := tm.Tempo(1000); bpm != 60 {
+		t.Errorf("Tempo(1000) = %f, want 60", bpm)
+	}
+}
+
+func TestTempoMapSMPTE(t *testing.T) {
+	// 25 frames per second, with 40 ticks per frame, gives 1000 ticks per second
+	tm := NewTempoMap(uint16(0x100-25)<<8|40, 120)
+	tm.SetTempo(100, 60)
+	if !tm.SMPTE() {
+		t.Error("SMPTE() = false, want true")
+	}
+	if d := tm.TicksToDuration(2500); d != 2500*time.Millisecond {
+		t.Errorf("TicksToDuration(2500) = %v, want 2.5s", d)
+	}
+}
+
+func TestAddNoteWithTempoChange(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	conductor := NewTrack()
+	tempo := NewTempo(60)
+	tempo.Tick = 960
+	conductor.AddEvent(tempo)
+	m.AddTrack(conductor)
+
+	track := NewTrack()
+	m.AddTrack(track)
+	m.AddNote(track, &Note{
+		Frequency:  440,
+		Duration:   time.Second,
+		Velocity:   DefaultNoteVelocity,
+		Channel:    DefaultNoteChannel,
+		EventDelay: 2 * time.Second,
+	})
+
+	// The note starts 1 second after the tempo change, and lasts for 480 ticks
+	noteOn, noteOff := track.Events[len(track.Events)-2], track.Events[len(track.Events)-1]
+	if noteOn.Tick != 1440 || noteOff.Tick != 1920 {
+		t.Errorf("Note plays from tick %d to %d, want 1440 to 1920", noteOn.Tick, noteOff.Tick)
+	}
+}
@@ -0,0 +1,230 @@
+package midi
+
+import (
+	"fmt"
+	"math"
+)
+
+// Transform changes the events in all the tracks of a MIDI song
+type Transform func(m *MIDI) error
+
+// Apply applies transforms to the events in all the tracks, in order
+func (m *MIDI) Apply(transforms ...Transform) error {
+	for _, transform := range transforms {
+		if err := transform(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forEachEvent calls f for each event in all the tracks
+func (m *MIDI) forEachEvent(f func(e *Event)) {
+	for _, t := range m.Tracks {
+		for _, e := range t.Events {
+			f(e)
+		}
+	}
+}
+
+// isNoteOn checks if an event is a "note on" event with a velocity above 0
+func (e *Event) isNoteOn() bool {
+	return e.Type == NoteOn && len(e.Data) > 1 && e.Data[1] > 0
+}
+
+// Transpose creates a Transform that moves the keys of all notes by a number of
+// semitones, and keeps them from the low to the high key. The drum channel is not
+// transposed, since its keys are different sounds.
+func Transpose(semitones int, low, high uint8) Transform {
+	return func(m *MIDI) error {
+		if low > high || high > 127 {
+			return fmt.Errorf("invalid key range %d to %d", low, high)
+		}
+		m.forEachEvent(func(e *Event) {
+			switch e.Type {
+			case NoteOn, NoteOff, PolyphonicKeyPressure:
+			default:
+				return
+			}
+			if e.Channel == DrumChannel || len(e.Data) < 1 {
+				return
+			}
+			key := int(e.Data[0]) + semitones
+			e.Data[0] = uint8(math.Max(float64(low), math.Min(float64(high), float64(key))))
+		})
+		return nil
+	}
+}
+
+// velocityTransform creates a Transform that changes the velocities of all
+// "note on" events, and keeps them from 1 to 127
+func velocityTransform(f func(velocity float64) float64) Transform {
+	return func(m *MIDI) error {
+		m.forEachEvent(func(e *Event) {
+			if e.isNoteOn() {
+				e.Data[1] = clampVelocity(f(float64(e.Data[1])))
+			}
+		})
+		return nil
+	}
+}
+
+// ScaleVelocity creates a Transform that multiplies the velocities of all notes by a factor
+func ScaleVelocity(factor float64) Transform {
+	return velocityTransform(func(velocity float64) float64 {
+		return velocity * factor
+	})
+}
+
+// CompressVelocity creates a Transform that reduces the velocities above the
+// threshold, by dividing the difference from the threshold by the ratio
+func CompressVelocity(threshold uint8, ratio float64) Transform {
+	return func(m *MIDI) error {
+		if ratio <= 0 {
+			return fmt.Errorf("invalid compression ratio %g, must be above 0", ratio)
+		}
+		return velocityTransform(func(velocity float64) float64 {
+			if velocity <= float64(threshold) {
+				return velocity
+			}
+			return float64(threshold) + (velocity-float64(threshold))/ratio
+		})(m)
+	}
+}
+
+// VelocityCurve creates a Transform that changes the velocities of all notes with a
+// curve, where an exponent above 1 makes soft notes softer, and below 1 makes them louder
+func VelocityCurve(exponent float64) Transform {
+	return func(m *MIDI) error {
+		if exponent <= 0 {
+			return fmt.Errorf("invalid velocity curve exponent %g, must be above 0", exponent)
+		}
+		return velocityTransform(func(velocity float64) float64 {
+			return 127 * math.Pow(velocity/127, exponent)
+		})(m)
+	}
+}
+
+// RemapChannels creates a Transform that moves the events on the channels in the
+// mapping to other channels, from 1 to 16. The programs of the channels are moved too.
+func RemapChannels(mapping map[uint8]uint8) Transform {
+	return func(m *MIDI) error {
+		for from, to := range mapping {
+			if from < 1 || from > 16 || to < 1 || to > 16 {
+				return fmt.Errorf("invalid channel mapping from %d to %d, channels must be from 1 to 16", from, to)
+			}
+		}
+		remap := func(channel uint8) uint8 {
+			if to, ok := mapping[channel]; ok {
+				return to
+			}
+			return channel
+		}
+		m.forEachEvent(func(e *Event) {
+			if isChannelMessage(e.Type) {
+				e.Channel = remap(e.Channel)
+			}
+		})
+
+		programs := make(map[uint8]uint8)
+		for channel, program := range m.ChannelProgram {
+			if _, moved := mapping[channel]; !moved {
+				programs[channel] = program
+			}
+		}
+		for channel, program := range m.ChannelProgram {
+			if _, moved := mapping[channel]; moved {
+				programs[remap(channel)] = program
+			}
+		}
+		m.ChannelProgram = programs
+
+		// Keep the pitch bend, tuning and bank state of AddNote on the same channels as the events
+		if m.bendSpans != nil {
+			spans := make(map[uint8][]bendSpan)
+			for channel, s := range m.bendSpans {
+				spans[remap(channel)] = append(spans[remap(channel)], s...)
+			}
+			m.bendSpans = spans
+		}
+		if m.bendRangeSent != nil {
+			sent := make(map[uint8]bool)
+			for channel := range m.bendRangeSent {
+				sent[remap(channel)] = true
+			}
+			m.bendRangeSent = sent
+		}
+		if m.tuningSelected != nil {
+			selected := make(map[uint8]bool)
+			for channel := range m.tuningSelected {
+				selected[remap(channel)] = true
+			}
+			m.tuningSelected = selected
+		}
+		if m.variations != nil {
+			variations := make(map[uint8]uint8)
+			for channel, variation := range m.variations {
+				variations[remap(channel)] = variation
+			}
+			m.variations = variations
+		}
+		return nil
+	}
+}
+
+// RemapPrograms creates a Transform that changes the programs in the mapping to other
+// programs, in program change events and in the programs of the channels
+func RemapPrograms(mapping map[uint8]uint8) Transform {
+	return func(m *MIDI) error {
+		for from, to := range mapping {
+			if from > 127 || to > 127 {
+				return fmt.Errorf("invalid program mapping from %d to %d, programs must be from 0 to 127", from, to)
+			}
+		}
+		remap := func(program uint8) uint8 {
+			if to, ok := mapping[program]; ok {
+				return to
+			}
+			return program
+		}
+		m.forEachEvent(func(e *Event) {
+			if !isChannelMessage(e.Type) {
+				return
+			}
+			e.Program = remap(e.Program)
+			if e.Type == ProgramChange && len(e.Data) > 0 {
+				e.Data[0] = remap(e.Data[0])
+			}
+		})
+		for channel, program := range m.ChannelProgram {
+			m.ChannelProgram[channel] = remap(program)
+		}
+		return nil
+	}
+}
+
+// TimeStretch creates a Transform that multiplies the ticks of all events by a
+// factor, so that a factor of 2 makes the song twice as long at the same tempo
+func TimeStretch(factor float64) Transform {
+	return func(m *MIDI) error {
+		if factor <= 0 {
+			return fmt.Errorf("invalid time stretch factor %g, must be above 0", factor)
+		}
+		stretch := func(tick uint32) uint32 {
+			return uint32(math.Min(math.MaxUint32, math.Round(float64(tick)*factor)))
+		}
+		for _, t := range m.Tracks {
+			for _, e := range t.Events {
+				e.Tick = stretch(e.Tick)
+			}
+			t.SortEvents()
+		}
+		for channel, spans := range m.bendSpans {
+			for i := range spans {
+				spans[i].start, spans[i].end = stretch(spans[i].start), stretch(spans[i].end)
+			}
+			m.bendSpans[channel] = spans
+		}
+		return nil
+	}
+}
@@ -0,0 +1,134 @@
+package midi
+
+import (
+	"testing"
+	"time"
+)
+
+// transformSong returns a song with a piano note on channel 1, a guitar note on
+// channel 2 and a drum note
+func transformSong(t *testing.T) *MIDI {
+	m := NewMIDI(1, 96, 120)
+	track := NewTrack()
+	notes := []*Note{
+		{Frequency: 440, Duration: time.Second / 2, Velocity: 100, Channel: 1, Program: 0},
+		{Frequency: 220, Duration: time.Second / 2, Velocity: 40, Channel: 2, Program: 24, EventDelay: time.Second / 2},
+		{Drum: "Acoustic Snare", Duration: time.Second / 4, Velocity: 120},
+	}
+	for _, note := range notes {
+		if err := m.AddNote(track, note); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.AddTrack(track)
+	return m
+}
+
+// noteOns returns the "note on" events in the first track, sorted by channel
+func noteOns(m *MIDI) map[uint8]*Event {
+	events := make(map[uint8]*Event)
+	for _, e := range m.Tracks[0].Events {
+		if e.Type == NoteOn {
+			events[e.Channel] = e
+		}
+	}
+	return events
+}
+
+func TestTranspose(t *testing.T) {
+	m := transformSong(t)
+	if err := m.Apply(Transpose(14, 0, 80)); err != nil {
+		t.Fatal(err)
+	}
+	notes := noteOns(m)
+	if notes[1].Data[0] != 80 || notes[2].Data[0] != 71 || notes[DrumChannel].Data[0] != 38 {
+		t.Errorf("keys = %d, %d and %d, want 80, 71 and 38", notes[1].Data[0], notes[2].Data[0], notes[DrumChannel].Data[0])
+	}
+	// The "note off" events match the "note on" events
+	for _, note := range m.Tracks[0].Notes() {
+		if note.DurationTicks == 0 {
+			t.Errorf("note %s on channel %d does not end", note.Pitch, note.Channel)
+		}
+	}
+	if err := m.Apply(Transpose(1, 100, 20)); err == nil {
+		t.Errorf("expected an error for an invalid key range")
+	}
+}
+
+func TestVelocityTransforms(t *testing.T) {
+	m := transformSong(t)
+	if err := m.Apply(ScaleVelocity(1.5)); err != nil {
+		t.Fatal(err)
+	}
+	notes := noteOns(m)
+	if notes[1].Data[1] != 127 || notes[2].Data[1] != 60 {
+		t.Errorf("scaled velocities = %d and %d, want 127 and 60", notes[1].Data[1], notes[2].Data[1])
+	}
+
+	m = transformSong(t)
+	if err := m.Apply(CompressVelocity(80, 2), VelocityCurve(0.5)); err != nil {
+		t.Fatal(err)
+	}
+	notes = noteOns(m)
+	// 100 is compressed to 90, and the curve gives 127 * sqrt(90 / 127)
+	if notes[1].Data[1] != 107 || notes[2].Data[1] != 71 {
+		t.Errorf("velocities = %d and %d, want 107 and 71", notes[1].Data[1], notes[2].Data[1])
+	}
+	// "note off" events are not changed
+	for _, e := range m.Tracks[0].Events {
+		if e.Type == NoteOff && e.Data[1] != 0 {
+			t.Errorf("the velocity of a note off event was changed to %d", e.Data[1])
+		}
+	}
+}
+
+func TestRemapChannelsAndPrograms(t *testing.T) {
+	m := transformSong(t)
+	if err := m.Apply(RemapChannels(map[uint8]uint8{1: 2, 2: 1}), RemapPrograms(map[uint8]uint8{24: 25})); err != nil {
+		t.Fatal(err)
+	}
+	notes := noteOns(m)
+	if notes[1].Data[0] != 57 || notes[2].Data[0] != 69 {
+		t.Errorf("the channels were not swapped")
+	}
+	if m.GetProgram(1) != 25 || m.GetProgram(2) != 0 {
+		t.Errorf("programs = %d and %d, want 25 and 0", m.GetProgram(1), m.GetProgram(2))
+	}
+	for _, e := range m.Tracks[0].Events {
+		if e.Type == ProgramChange && (e.Channel != 1 || e.Data[0] != 25) {
+			t.Errorf("program change to %d on channel %d, want 25 on channel 1", e.Data[0], e.Channel)
+		}
+	}
+	// AddNote does not need a program change for the remapped program
+	before := len(m.Tracks[0].Events)
+	if err := m.AddNote(m.Tracks[0], &Note{Frequency: 330, Duration: time.Second, Velocity: 64, Channel: 1, Program: 25, EventDelay: 2 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Tracks[0].Events) != before+2 {
+		t.Errorf("got %d new events, want 2", len(m.Tracks[0].Events)-before)
+	}
+
+	if err := m.Apply(RemapChannels(map[uint8]uint8{1: 17})); err == nil {
+		t.Errorf("expected an error for an invalid channel")
+	}
+}
+
+func TestTimeStretch(t *testing.T) {
+	m := transformSong(t)
+	if err := m.Apply(TimeStretch(1.5)); err != nil {
+		t.Fatal(err)
+	}
+	notes := m.Notes(m.Tracks[0])
+	want := []time.Duration{0, 0, 750 * time.Millisecond}
+	for i, note := range notes {
+		if note.EventDelay != want[i] {
+			t.Errorf("note %d starts at %v, want %v", i, note.EventDelay, want[i])
+		}
+	}
+	if notes[2].Duration != 750*time.Millisecond {
+		t.Errorf("duration = %v, want 750ms", notes[2].Duration)
+	}
+	if err := m.Apply(TimeStretch(0)); err == nil {
+		t.Errorf("expected an error for a factor of 0")
+	}
+}
@@ -0,0 +1,327 @@
+package midi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Tuning converts MIDI note numbers to frequencies
+type Tuning interface {
+	Frequency(note int) float64
+}
+
+// EqualTemperament is twelve-tone equal temperament, with the given frequency
+// for A4, like 440, 442, 432 or 415
+type EqualTemperament struct {
+	A4 float64
+}
+
+// Frequency returns the frequency of a MIDI note number
+func (t EqualTemperament) Frequency(note int) float64 {
+	return t.A4 * math.Pow(2, float64(note-69)/12)
+}
+
+// ScaleTuning is a tuning built from a repeating scale, like just intonation or
+// a tuning loaded from a Scala file
+type ScaleTuning struct {
+	Description string
+	Cents       []float64 // The scale degrees in cents, without the first degree (0 cents). The last one is the period, usually 1200.
+	Keyboard    KeyboardMapping
+}
+
+// KeyboardMapping maps MIDI notes to the degrees of a ScaleTuning, like a Scala .kbm file
+type KeyboardMapping struct {
+	FirstNote          int     // The first mapped MIDI note
+	LastNote           int     // The last mapped MIDI note
+	MiddleNote         int     // The MIDI note where the first scale degree is
+	ReferenceNote      int     // The MIDI note with the reference frequency
+	ReferenceFrequency float64 // The frequency of the reference note
+	OctaveDegree       int     // The scale degree that is the formal octave, or 0 for the size of the scale
+	Mapping            []int   // The scale degree of each key from the middle note, or -1 if unmapped. Empty for a linear mapping.
+}
+
+// NewKeyboardMapping creates a linear KeyboardMapping, where the first scale
+// degree is at the middle note, and the reference note has the given frequency
+func NewKeyboardMapping(middleNote, referenceNote int, referenceFrequency float64) KeyboardMapping {
+	return KeyboardMapping{
+		FirstNote:          0,
+		LastNote:           127,
+		MiddleNote:         middleNote,
+		ReferenceNote:      referenceNote,
+		ReferenceFrequency: referenceFrequency,
+	}
+}
+
+// Frequency returns the frequency of a MIDI note number, or 0 if the note is not mapped
+func (t *ScaleTuning) Frequency(note int) float64 {
+	if len(t.Cents) == 0 {
+		return 0
+	}
+	k := t.Keyboard
+	if note < k.FirstNote || note > k.LastNote {
+		return 0
+	}
+	degree, ok := t.degree(note)
+	if !ok {
+		return 0
+	}
+	referenceDegree, ok := t.degree(k.ReferenceNote)
+	if !ok {
+		return 0
+	}
+	return k.ReferenceFrequency * math.Pow(2, (t.degreeCents(degree)-t.degreeCents(referenceDegree))/1200)
+}
+
+// degree returns the scale degree of a MIDI note, counted from the middle note
+func (t *ScaleTuning) degree(note int) (int, bool) {
+	k := t.Keyboard
+	offset := note - k.MiddleNote
+	if len(k.Mapping) == 0 {
+		return offset, true
+	}
+	degree := k.Mapping[mod(offset, len(k.Mapping))]
+	if degree < 0 {
+		return 0, false
+	}
+	octaveDegree := k.OctaveDegree
+	if octaveDegree == 0 {
+		octaveDegree = len(t.Cents)
+	}
+	return degree + floorDiv(offset, len(k.Mapping))*octaveDegree, true
+}
+
+// degreeCents returns the cents of a scale degree, which may be outside of the first period
+func (t *ScaleTuning) degreeCents(degree int) float64 {
+	size := len(t.Cents)
+	period := t.Cents[size-1]
+	index := mod(degree, size)
+	cents := float64(floorDiv(degree, size)) * period
+	if index > 0 {
+		cents += t.Cents[index-1]
+	}
+	return cents
+}
+
+// ratiosToCents converts frequency ratios to cents
+func ratiosToCents(ratios ...float64) []float64 {
+	cents := make([]float64, len(ratios))
+	for i, ratio := range ratios {
+		cents[i] = 1200 * math.Log2(ratio)
+	}
+	return cents
+}
+
+// tonicKeyboard returns a keyboard mapping where the tonic has its equal temperament frequency, with A4 at 440Hz
+func tonicKeyboard(tonic int) KeyboardMapping {
+	return NewKeyboardMapping(tonic, tonic, midiNumberToFrequency(float64(tonic)))
+}
+
+// JustIntonation creates a five-limit just intonation tuning from the given tonic
+func JustIntonation(tonic int) *ScaleTuning {
+	return &ScaleTuning{
+		Description: "5-limit just intonation",
+		Cents:       ratiosToCents(16.0/15, 9.0/8, 6.0/5, 5.0/4, 4.0/3, 45.0/32, 3.0/2, 8.0/5, 5.0/3, 9.0/5, 15.0/8, 2),
+		Keyboard:    tonicKeyboard(tonic),
+	}
+}
+
+// Pythagorean creates a Pythagorean tuning from the given tonic, built from pure fifths
+func Pythagorean(tonic int) *ScaleTuning {
+	return &ScaleTuning{
+		Description: "Pythagorean tuning",
+		Cents:       fifthsScale(1200 * math.Log2(3.0/2)),
+		Keyboard:    tonicKeyboard(tonic),
+	}
+}
+
+// Meantone creates a quarter-comma meantone tuning from the given tonic, with pure major thirds
+func Meantone(tonic int) *ScaleTuning {
+	return &ScaleTuning{
+		Description: "1/4-comma meantone",
+		Cents:       fifthsScale(1200 * math.Log2(math.Pow(5, 0.25))),
+		Keyboard:    tonicKeyboard(tonic),
+	}
+}
+
+// fifthsScale creates a 12 note scale from a chain of fifths of the given size,
+// from 3 fifths below the tonic to 8 fifths above it
+func fifthsScale(fifth float64) []float64 {
+	cents := make([]float64, 12)
+	for k := -3; k <= 8; k++ {
+		semitone := mod(7*k, 12)
+		if semitone == 0 {
+			continue
+		}
+		cents[semitone-1] = math.Mod(math.Mod(float64(k)*fifth, 1200)+1200, 1200)
+	}
+	cents[11] = 1200
+	return cents
+}
+
+// FrequencyToPitch returns the pitch in a tuning that is closest to a frequency, with
+// the difference in cents. If the tuning is nil, A4 is 440Hz with equal temperament.
+func FrequencyToPitch(frequency float64, tuning Tuning) Pitch {
+	if tuning == nil {
+		exact := math.Log2(frequency/440)*12 + 69
+		number := math.Floor(exact + 0.5)
+		return Pitch{Number: int(number), Cents: (exact - number) * 100}
+	}
+	var best Pitch
+	found := false
+	for note := 0; note <= 127; note++ {
+		noteFrequency := tuning.Frequency(note)
+		if noteFrequency <= 0 {
+			continue
+		}
+		cents := 1200 * math.Log2(frequency/noteFrequency)
+		if !found || math.Abs(cents) < math.Abs(best.Cents) {
+			best = Pitch{Number: note, Cents: cents}
+			found = true
+		}
+	}
+	return best
+}
+
+// LoadScala loads a tuning from a Scala .scl file
+func LoadScala(filename string) (*ScaleTuning, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadScala(f)
+}
+
+// ReadScala reads a tuning in the Scala .scl format. The tuning starts at middle C,
+// with the equal temperament frequency, until a keyboard mapping is set.
+func ReadScala(r io.Reader) (*ScaleTuning, error) {
+	lines, err := scalaLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("scala file is missing the description or the number of notes")
+	}
+	t := &ScaleTuning{
+		Description: lines[0],
+		Keyboard:    tonicKeyboard(60),
+	}
+	count, err := strconv.Atoi(firstField(lines[1]))
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid number of notes in scala file: %q", lines[1])
+	}
+	if len(lines)-2 < count {
+		return nil, fmt.Errorf("scala file has %d notes, but should have %d", len(lines)-2, count)
+	}
+	for _, line := range lines[2 : 2+count] {
+		cents, err := parseScalaPitch(firstField(line))
+		if err != nil {
+			return nil, err
+		}
+		t.Cents = append(t.Cents, cents)
+	}
+	if count == 0 {
+		// A scale with only the first degree repeats every octave
+		t.Cents = []float64{1200}
+	}
+	return t, nil
+}
+
+// parseScalaPitch parses a pitch in a Scala file, in cents if it contains a
+// period, or as a ratio like "3/2" or "2" otherwise
+func parseScalaPitch(s string) (float64, error) {
+	if strings.Contains(s, ".") {
+		cents, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid cents value in scala file: %q", s)
+		}
+		return cents, nil
+	}
+	numerator, denominator, found := strings.Cut(s, "/")
+	n, err := strconv.ParseFloat(numerator, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid ratio in scala file: %q", s)
+	}
+	d := 1.0
+	if found {
+		if d, err = strconv.ParseFloat(denominator, 64); err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid ratio in scala file: %q", s)
+		}
+	}
+	return 1200 * math.Log2(n/d), nil
+}
+
+// LoadKeyboardMapping loads a keyboard mapping from a Scala .kbm file
+func LoadKeyboardMapping(filename string) (KeyboardMapping, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return KeyboardMapping{}, err
+	}
+	defer f.Close()
+	return ReadKeyboardMapping(f)
+}
+
+// ReadKeyboardMapping reads a keyboard mapping in the Scala .kbm format
+func ReadKeyboardMapping(r io.Reader) (KeyboardMapping, error) {
+	var k KeyboardMapping
+	lines, err := scalaLines(r)
+	if err != nil {
+		return k, err
+	}
+	if len(lines) < 7 {
+		return k, fmt.Errorf("keyboard mapping file is too short")
+	}
+	var values [7]float64
+	for i := range values {
+		if values[i], err = strconv.ParseFloat(firstField(lines[i]), 64); err != nil {
+			return k, fmt.Errorf("invalid value in keyboard mapping file: %q", lines[i])
+		}
+	}
+	size := int(values[0])
+	k.FirstNote, k.LastNote, k.MiddleNote = int(values[1]), int(values[2]), int(values[3])
+	k.ReferenceNote, k.ReferenceFrequency, k.OctaveDegree = int(values[4]), values[5], int(values[6])
+	if size < 0 || len(lines)-7 < size {
+		return k, fmt.Errorf("keyboard mapping file should have %d keys", size)
+	}
+	for _, line := range lines[7 : 7+size] {
+		field := firstField(line)
+		if field == "x" || field == "X" {
+			k.Mapping = append(k.Mapping, -1)
+			continue
+		}
+		degree, err := strconv.Atoi(field)
+		if err != nil || degree < 0 {
+			return k, fmt.Errorf("invalid key in keyboard mapping file: %q", line)
+		}
+		k.Mapping = append(k.Mapping, degree)
+	}
+	return k, nil
+}
+
+// scalaLines returns the lines of a Scala file, without the comments
+func scalaLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return lines, scanner.Err()
+}
+
+// firstField returns the first word of a line, since the rest may be a comment
+func firstField(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
@@ -0,0 +1,172 @@
+package midi
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ratio returns the frequency ratio between two notes in a tuning
+func ratio(tuning Tuning, from, to int) float64 {
+	return tuning.Frequency(to) / tuning.Frequency(from)
+}
+
+func TestEqualTemperament(t *testing.T) {
+	tuning := EqualTemperament{A4: 432}
+	if f := tuning.Frequency(69); f != 432 {
+		t.Errorf("Frequency(69) with A4 at 432Hz = %f, want 432", f)
+	}
+	if f := tuning.Frequency(81); math.Abs(f-864) > 0.001 {
+		t.Errorf("Frequency(81) with A4 at 432Hz = %f, want 864", f)
+	}
+}
+
+func TestHistoricalTunings(t *testing.T) {
+	just := JustIntonation(60)
+	if r := ratio(just, 60, 67); math.Abs(r-1.5) > 1e-9 {
+		t.Errorf("Just fifth = %f, want 1.5", r)
+	}
+	if r := ratio(just, 60, 64); math.Abs(r-1.25) > 1e-9 {
+		t.Errorf("Just major third = %f, want 1.25", r)
+	}
+	if f := just.Frequency(60); math.Abs(f-261.6256) > 0.001 {
+		t.Errorf("Just intonation tonic = %f, want 261.6256", f)
+	}
+	if r := ratio(just, 60, 72); math.Abs(r-2) > 1e-9 {
+		t.Errorf("Just octave = %f, want 2", r)
+	}
+
+	pythagorean := Pythagorean(62)
+	if r := ratio(pythagorean, 62, 69); math.Abs(r-1.5) > 1e-9 {
+		t.Errorf("Pythagorean fifth = %f, want 1.5", r)
+	}
+	if r := ratio(pythagorean, 62, 66); math.Abs(r-81.0/64) > 1e-9 {
+		t.Errorf("Pythagorean major third = %f, want 81/64", r)
+	}
+
+	meantone := Meantone(60)
+	if r := ratio(meantone, 60, 64); math.Abs(r-1.25) > 1e-9 {
+		t.Errorf("Meantone major third = %f, want 1.25", r)
+	}
+	if r := ratio(meantone, 48, 64); math.Abs(r-2.5) > 1e-9 {
+		t.Errorf("Meantone major tenth = %f, want 2.5", r)
+	}
+}
+
+const testScala = `! test.scl
+!
+Bohlen-Pierce-like scale with 3 notes
+ 3
+!
+ 5/4
+ 701.955 cents
+ 2
+`
+
+const testKeyboardMapping = `! test.kbm
+4
+0
+127
+60
+60
+261.6256
+3
+! Mapping
+0
+1
+x
+2
+`
+
+func TestScala(t *testing.T) {
+	tuning, err := ReadScala(strings.NewReader(testScala))
+	if err != nil {
+		t.Fatalf("ReadScala failed: %v", err)
+	}
+	if tuning.Description != "Bohlen-Pierce-like scale with 3 notes" || len(tuning.Cents) != 3 {
+		t.Fatalf("ReadScala = %q with %d notes", tuning.Description, len(tuning.Cents))
+	}
+	if r := ratio(tuning, 60, 61); math.Abs(r-1.25) > 1e-9 {
+		t.Errorf("Second degree ratio = %f, want 1.25", r)
+	}
+	if r := ratio(tuning, 60, 63); math.Abs(r-2) > 1e-9 {
+		t.Errorf("Period ratio = %f, want 2", r)
+	}
+
+	tuning.Keyboard, err = ReadKeyboardMapping(strings.NewReader(testKeyboardMapping))
+	if err != nil {
+		t.Fatalf("ReadKeyboardMapping failed: %v", err)
+	}
+	if f := tuning.Frequency(62); f != 0 {
+		t.Errorf("Frequency of an unmapped key = %f, want 0", f)
+	}
+	if r := ratio(tuning, 60, 63); math.Abs(r-1.5) > 1e-6 {
+		t.Errorf("Ratio of the fourth key = %f, want 1.5", r)
+	}
+	if r := ratio(tuning, 60, 64); math.Abs(r-2) > 1e-9 {
+		t.Errorf("Ratio of the mapped octave = %f, want 2", r)
+	}
+
+	if _, err := ReadScala(strings.NewReader("bad\n2\n3/2\n")); err == nil {
+		t.Error("ReadScala should fail when notes are missing")
+	}
+}
+
+func TestFrequencyToPitch(t *testing.T) {
+	p := FrequencyToPitch(445, nil)
+	if p.Number != 69 || math.Abs(p.Cents-19.56) > 0.01 {
+		t.Errorf("FrequencyToPitch(445, nil) = %d %+f cents, want 69 +19.56 cents", p.Number, p.Cents)
+	}
+	just := JustIntonation(60)
+	p = FrequencyToPitch(just.Frequency(64), just)
+	if p.Number != 64 || math.Abs(p.Cents) > 1e-6 {
+		t.Errorf("FrequencyToPitch of a just major third = %d %+f cents, want 64 +0 cents", p.Number, p.Cents)
+	}
+}
+
+func TestAddNoteWithTuning(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	m.PitchBendRange = 2
+	m.Tuning = JustIntonation(60)
+	track := NewTrack()
+	m.AddTrack(track)
+
+	// A just major third is 13.7 cents lower than in equal temperament
+	if err := m.AddNote(track, &Note{Pitch: &Pitch{Number: 64}, Duration: time.Second, Channel: 1}); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	for _, e := range track.Events {
+		if value, ok := e.PitchBendValue(); ok {
+			if want := -560; value != want {
+				t.Errorf("Pitch bend for a just major third = %d, want %d", value, want)
+			}
+		}
+	}
+}
+
+func TestAddNoteWithTuningErrors(t *testing.T) {
+	cents := make([]float64, 12)
+	for i := range cents {
+		cents[i] = float64(i+1) * 100
+	}
+	keyboard := NewKeyboardMapping(60, 69, 440)
+	keyboard.Mapping = []int{0, -1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11} // C# is not mapped
+	m := NewMIDI(1, 480, 120)
+	m.PitchBendRange = 2
+	m.Tuning = &ScaleTuning{Cents: cents, Keyboard: keyboard}
+	track := NewTrack()
+	m.AddTrack(track)
+
+	for _, number := range []int{61, 200} {
+		if err := m.AddNote(track, &Note{Pitch: &Pitch{Number: number}, Duration: time.Second, Channel: 1}); err == nil {
+			t.Errorf("AddNote should fail for note %d", number)
+		}
+	}
+	if len(track.Events) != 0 {
+		t.Errorf("No events should be added for notes that fail, got %d", len(track.Events))
+	}
+	if err := m.AddNote(track, &Note{Pitch: &Pitch{Number: 60}, Duration: time.Second, Channel: 1}); err != nil {
+		t.Errorf("AddNote failed for a mapped note: %v", err)
+	}
+}
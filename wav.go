@@ -0,0 +1,53 @@
+package midi
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// WriteWAV writes mono samples from -1 to 1 as a 16-bit PCM WAV file.
+// Samples outside of the range are clipped.
+func WriteWAV(w io.Writer, samples []float64, sampleRate int) error {
+	const bytesPerSample = 2
+	dataSize := uint32(len(samples) * bytesPerSample)
+	header := struct {
+		RIFF          [4]byte
+		Size          uint32
+		WAVE          [4]byte
+		Fmt           [4]byte
+		FmtSize       uint32
+		Format        uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		RIFF:          [4]byte{'R', 'I', 'F', 'F'},
+		Size:          36 + dataSize,
+		WAVE:          [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:           [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		Format:        1, // PCM
+		Channels:      1,
+		SampleRate:    uint32(sampleRate),
+		ByteRate:      uint32(sampleRate * bytesPerSample),
+		BlockAlign:    bytesPerSample,
+		BitsPerSample: 8 * bytesPerSample,
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      dataSize,
+	}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	data := make([]byte, dataSize)
+	for i, sample := range samples {
+		value := int16(math.Round(math.Max(-1, math.Min(1, sample)) * math.MaxInt16))
+		binary.LittleEndian.PutUint16(data[i*bytesPerSample:], uint16(value))
+	}
+	_, err := w.Write(data)
+	return err
+}
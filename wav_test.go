@@ -0,0 +1,30 @@
+package midi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestWriteWAV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteWAV(&buf, []float64{0, 0.5, -1, 2}, 8000); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if len(data) != 44+8 {
+		t.Fatalf("got %d bytes, want %d", len(data), 44+8)
+	}
+	if size := binary.LittleEndian.Uint32(data[4:]); size != 36+8 {
+		t.Errorf("RIFF size = %d, want %d", size, 36+8)
+	}
+	if rate := binary.LittleEndian.Uint32(data[24:]); rate != 8000 {
+		t.Errorf("sample rate = %d, want 8000", rate)
+	}
+	want := []int16{0, 16384, -32767, 32767}
+	for i, w := range want {
+		if got := int16(binary.LittleEndian.Uint16(data[44+2*i:])); got != w {
+			t.Errorf("sample %d = %d, want %d", i, got, w)
+		}
+	}
+}
@@ -2,26 +2,16 @@ package midi
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 )
 
+// WriteMIDI writes the MIDI data to an io.Writer
 func WriteMIDI(w io.Writer, m *MIDI) error {
-	// Write MIDI header
-	if err := writeMIDIHeader(w, m); err != nil {
-		return err
-	}
-
-	// Write each track
-	for _, track := range m.Tracks {
-		if err := writeTrack(w, track); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return NewEncoder(w).EncodeMIDI(m)
 }
 
-func writeMIDIHeader(w io.Writer, m *MIDI) error {
+func writeMIDIHeader(w io.Writer, m *MIDI, numTracks uint16) error {
 	// Chunk type: "MThd"
 	if _, err := w.Write([]byte("MThd")); err != nil {
 		return err
@@ -38,7 +28,6 @@ func writeMIDIHeader(w io.Writer, m *MIDI) error {
 	}
 
 	// Number of tracks
-	numTracks := uint16(len(m.Tracks))
 	if err := writeMIDIUint16(w, numTracks); err != nil {
 		return err
 	}
@@ -47,13 +36,39 @@ func writeMIDIHeader(w io.Writer, m *MIDI) error {
 	return writeMIDIUint16(w, m.Division)
 }
 
-func writeTrack(w io.Writer, t *Track) error {
+func writeTrack(w io.Writer, t *Track, enc *Encoder) error {
 	// Buffer the track data
 	buf := new(bytes.Buffer)
 
+	// Each track starts without running status
+	trackEncoder := &Encoder{
+		w:               buf,
+		SMF:             true,
+		RunningStatus:   enc.RunningStatus,
+		NoteOffAsNoteOn: enc.NoteOffAsNoteOn,
+	}
+
+	// Sort the events by tick, and end the track with a single End of Track event
+	events := make([]*Event, 0, len(t.Events)+1)
+	var endTick uint32
+	for _, event := range sortedEvents(t.Events) {
+		if event.Tick > endTick {
+			endTick = event.Tick
+		}
+		if !event.IsMeta(MetaEndOfTrack) {
+			events = append(events, event)
+		}
+	}
+	endOfTrack := NewEndOfTrack()
+	endOfTrack.Tick = endTick
+	events = append(events, endOfTrack)
+
+	// Calculate the delta times
+	updateDeltaTimes(events)
+
 	// Write each event to the buffer
-	for _, event := range t.Events {
-		if err := writeEvent(buf, event); err != nil {
+	for _, event := range events {
+		if err := trackEncoder.Encode(event); err != nil {
 			return err
 		}
 	}
@@ -77,21 +92,26 @@ func writeTrack(w io.Writer, t *Track) error {
 	return nil
 }
 
+// writeEvent writes an event with its delta time, like in a track
 func writeEvent(w io.Writer, e *Event) error {
-	// Write delta time
-	if err := writeVariableLengthQuantity(w, e.DeltaTime); err != nil {
-		return err
-	}
+	enc := NewEncoder(w)
+	enc.SMF = true
+	return enc.Encode(e)
+}
 
-	// Write event type
-	if err := writeMIDIUint8(w, e.Type); err != nil {
-		return err
+// statusByte returns the status byte of an event. For channel messages, the
+// event type is the high nibble and the channel (1 to 16) is stored in the low nibble.
+func statusByte(e *Event) (uint8, error) {
+	if !isChannelMessage(e.Type) {
+		return e.Type, nil
 	}
-
-	// Write event data
-	if _, err := w.Write(e.Data); err != nil {
-		return err
+	if e.Channel < 1 || e.Channel > 16 {
+		return 0, fmt.Errorf("invalid channel %d, must be from 1 to 16", e.Channel)
 	}
+	return e.Type&0xF0 | (e.Channel - 1), nil
+}
 
-	return nil
+// isChannelMessage checks if an event type is a channel voice message
+func isChannelMessage(eventType uint8) bool {
+	return eventType >= NoteOff && eventType < SystemExclusive
 }
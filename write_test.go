@@ -3,8 +3,10 @@
 package midi
 
 import (
+	"bytes"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestWriteMIDI(t *testing.T) {
@@ -13,14 +15,16 @@ func TestWriteMIDI(t *testing.T) {
 			{
 				Events: []*Event{
 					{
-						DeltaTime: 0,
-						Type:      EventNoteOn,
-						Data:      []byte{0x40, 0x60}, // Note 64, velocity 96
+						Tick:    0,
+						Type:    EventNoteOn,
+						Channel: 1,
+						Data:    []byte{0x40, 0x60}, // Note 64, velocity 96
 					},
 					{
-						DeltaTime: 480, // 1 quarter note later
-						Type:      EventNoteOff,
-						Data:      []byte{0x40, 0x00}, // Note 64, velocity 0
+						Tick:    480, // 1 quarter note later
+						Type:    EventNoteOff,
+						Channel: 1,
+						Data:    []byte{0x40, 0x00}, // Note 64, velocity 0
 					},
 				},
 			},
@@ -38,3 +42,60 @@ func TestWriteMIDI(t *testing.T) {
 		t.Fatalf("Failed to write MIDI: %v", err)
 	}
 }
+
+func TestWriteChannels(t *testing.T) {
+	m := NewMIDI(1, 480, 120)
+	for _, channel := range []uint8{1, 2, 10, 16} {
+		track := NewTrack()
+		m.AddTrack(track)
+		m.AddNote(track, &Note{
+			Frequency: 440,
+			Duration:  time.Second,
+			Velocity:  DefaultNoteVelocity,
+			Channel:   channel,
+			Program:   DefaultNoteProgram,
+		})
+	}
+
+	buf := new(bytes.Buffer)
+	if err := m.Write(buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	m2, err := Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	// The first track is the conductor track, with the tempo
+	if len(m2.Tracks) != len(m.Tracks)+1 {
+		t.Fatalf("Read %d tracks, want %d", len(m2.Tracks), len(m.Tracks)+1)
+	}
+	for i, track := range m2.Tracks[1:] {
+		want := m.Tracks[i].Events[0].Channel
+		for _, e := range track.Events {
+			if e.Type == EventMeta {
+				continue
+			}
+			if e.Channel != want {
+				t.Errorf("Track %d: event %X is on channel %d, want %d", i+1, e.Type, e.Channel, want)
+			}
+		}
+	}
+}
+
+func TestWriteInvalidChannel(t *testing.T) {
+	for _, channel := range []uint8{0, 17} {
+		e := &Event{Type: EventNoteOn, Channel: channel, Data: []byte{0x40, 0x60}}
+		if err := writeEvent(new(bytes.Buffer), e); err == nil {
+			t.Errorf("writeEvent should fail for channel %d", channel)
+		}
+	}
+	buf := new(bytes.Buffer)
+	e := &Event{Type: EventNoteOn, Channel: 16, Data: []byte{0x40, 0x60}}
+	if err := writeEvent(buf, e); err != nil {
+		t.Fatalf("writeEvent failed: %v", err)
+	}
+	if status := buf.Bytes()[1]; status != 0x9F {
+		t.Errorf("Status byte for a note on, on channel 16 = %X, want 9F", status)
+	}
+}